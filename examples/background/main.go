@@ -2,24 +2,38 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net/http"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/examples/internal/exampleclient"
 	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
 	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
+var (
+	serverURL = flag.String("server-url", "https://127.0.0.1:8999", "base URL of the basic service")
+	caFile    = flag.String("ca-file", "", "PEM encoded CA to trust, e.g. the file written by the server's -dev-tls-ca-file")
+	token     = flag.String("token", "", "API key or JWT sent as bearer token, for servers with -auth-mode set")
+	jobID     = flag.String("job-id", "", "ID of a running job to reattach to instead of starting a new one")
+)
+
 func main() {
-	client := basicV1connect.NewBasicServiceClient(http.DefaultClient, "https://127.0.0.1:8999", connect.WithGRPC())
+	flag.Parse()
+
+	httpClient, err := exampleclient.New(*caFile)
+	if err != nil {
+		log.Fatalf("error creating HTTP client: %v\n", err)
+	}
+	client := basicV1connect.NewBasicServiceClient(httpClient, *serverURL, connect.WithGRPC(), exampleclient.WithToken(*token))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: 4}))
+	stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: 4, JobId: *jobID}))
 	if err != nil {
 		log.Fatalf("error calling Background: %v\n", err)
 	}
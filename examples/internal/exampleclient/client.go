@@ -0,0 +1,77 @@
+// Package exampleclient builds the HTTP client shared by the example programs.
+package exampleclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"connectrpc.com/connect"
+)
+
+// New returns an HTTP client that trusts the system roots and, if caFile is set,
+// the PEM encoded CA in caFile, such as the one written by the server's
+// -dev-tls-ca-file flag.
+func New(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return http.DefaultClient, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			ForceAttemptHTTP2: true,
+		},
+	}, nil
+}
+
+// WithToken returns a client option sending token as bearer credentials on
+// every call, for servers with authentication enabled. An empty token sends none.
+func WithToken(token string) connect.ClientOption {
+	return connect.WithInterceptors(tokenInterceptor(token))
+}
+
+// tokenInterceptor sets the Authorization header of unary and streaming calls.
+type tokenInterceptor string
+
+// WrapUnary implements connect.Interceptor.
+func (t tokenInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if t != "" {
+			req.Header().Set("Authorization", "Bearer "+string(t))
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (t tokenInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		if t != "" {
+			conn.RequestHeader().Set("Authorization", "Bearer "+string(t))
+		}
+		return conn
+	}
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (tokenInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
@@ -0,0 +1,103 @@
+// Package admin provides the handlers of the admin listener: profiling, Prometheus
+// and expvar metrics and runtime information meant for operators, not for service
+// clients.
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Path      string `json:"path"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo returns the module version, VCS revision and Go version embedded
+// into the binary by the Go toolchain. Fields that are not available, e.g. in
+// binaries built without VCS information, are left empty.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{Version: "(devel)", GoVersion: runtime.Version()}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = build.Main.Path
+	if build.Main.Version != "" {
+		info.Version = build.Main.Version
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		case "vcs.time":
+			info.BuildTime = setting.Value
+		}
+	}
+	return info
+}
+
+// NewHandler returns the admin endpoints:
+//
+//	/debug/pprof/  runtime profiles of net/http/pprof
+//	/debug/vars    expvar metrics such as certificate reloads
+//	/metrics       Prometheus metrics served by metrics
+//	/buildinfo     version, commit and Go version as JSON
+//	/config        effective configuration as YAML
+//	/state         snapshot of the operations tracked by states as JSON
+func NewHandler(cfg *config.Config, states *utils.StateManager, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("GET /metrics", metrics)
+
+	buildInfo := ReadBuildInfo()
+	mux.HandleFunc("GET /buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, buildInfo)
+	})
+
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := cfg.Write(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(buf.Bytes())
+	})
+
+	mux.HandleFunc("GET /state", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, states.Snapshot())
+	})
+
+	return mux
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
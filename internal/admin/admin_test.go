@@ -0,0 +1,100 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/admin"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/metrics"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// get requests path from server and returns the response body.
+func get(t *testing.T, server *httptest.Server, path string) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := server.Client().Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Server.Addr = "0.0.0.0:9443"
+	states := utils.NewStateManager()
+	states.Start("job-1")
+	server := httptest.NewServer(admin.NewHandler(cfg, states, metrics.New(states).Handler()))
+	defer server.Close()
+
+	t.Run("should serve pprof profiles", func(t *testing.T) {
+		resp, body := get(t, server, "/debug/pprof/goroutine?debug=1")
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), "goroutine profile")
+	})
+
+	t.Run("should serve expvar metrics", func(t *testing.T) {
+		resp, body := get(t, server, "/debug/vars")
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `"memstats"`)
+	})
+
+	t.Run("should serve Prometheus metrics", func(t *testing.T) {
+		resp, body := get(t, server, "/metrics")
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `basic_background_jobs{state="STATE_PROCESS"} 1`)
+	})
+
+	t.Run("should serve build info", func(t *testing.T) {
+		resp, body := get(t, server, "/buildinfo")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var info admin.BuildInfo
+		require.NoError(t, json.Unmarshal(body, &info))
+		assert.Equal(t, runtime.Version(), info.GoVersion)
+		assert.NotEmpty(t, info.Version)
+	})
+
+	t.Run("should serve the effective configuration", func(t *testing.T) {
+		resp, body := get(t, server, "/config")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+
+		var served config.Config
+		require.NoError(t, yaml.Unmarshal(body, &served))
+		assert.Equal(t, *cfg, served)
+	})
+
+	t.Run("should serve a snapshot of the state manager", func(t *testing.T) {
+		resp, body := get(t, server, "/state")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var snapshot []utils.Snapshot
+		require.NoError(t, json.Unmarshal(body, &snapshot))
+		require.Len(t, snapshot, 1)
+		assert.Equal(t, "job-1", snapshot[0].Hash)
+		assert.Equal(t, "STATE_PROCESS", snapshot[0].State)
+	})
+
+	t.Run("should not serve the service handlers", func(t *testing.T) {
+		resp, _ := get(t, server, "/basic.v1.BasicService/Hello")
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
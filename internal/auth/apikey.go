@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// errInvalidAPIKey is returned for API keys that are not configured.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// apiKey is a configured API key, stored as digest so keys of any length are
+// compared in constant time.
+type apiKey struct {
+	principal string
+	digest    [sha256.Size]byte
+}
+
+// APIKeys authenticates requests by static API keys sent as bearer token or in
+// the X-Api-Key header.
+type APIKeys struct {
+	keys []apiKey
+}
+
+// LoadAPIKeys reads the API keys from the YAML file at path, a map of principal
+// name to key:
+//
+//	ci-pipeline: 3b7f0c...
+//	dashboard: 9a41e2...
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read API keys: %w", err)
+	}
+
+	var entries map[string]string
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse API keys %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("parse API keys %s: no keys defined", path)
+	}
+
+	keys := &APIKeys{}
+	for principal, key := range entries {
+		if key == "" {
+			return nil, fmt.Errorf("parse API keys %s: empty key for %q", path, principal)
+		}
+		keys.keys = append(keys.keys, apiKey{principal: principal, digest: sha256.Sum256([]byte(key))})
+	}
+	return keys, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeys) Authenticate(header http.Header) (*Principal, error) {
+	key := header.Get(APIKeyHeader)
+	if key == "" {
+		key, _ = bearerToken(header)
+	}
+	if key == "" {
+		return nil, errMissingCredentials
+	}
+
+	// Compare against every key so the timing does not reveal which one matched.
+	digest := sha256.Sum256([]byte(key))
+	var principal string
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], k.digest[:]) == 1 {
+			principal = k.principal
+		}
+	}
+	if principal == "" {
+		return nil, errInvalidAPIKey
+	}
+	return &Principal{Subject: principal, Method: config.AuthModeAPIKey}, nil
+}
@@ -0,0 +1,164 @@
+// Package auth authenticates the callers of the service. Credentials are
+// checked by an Authenticator, either static API keys or JWTs verified with a
+// local JSON Web Key Set, and the resulting principal is attached to the
+// context of the call.
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+)
+
+// APIKeyHeader carries an API key as alternative to a bearer token.
+const APIKeyHeader = "X-Api-Key"
+
+// errMissingCredentials is returned for requests without any credentials.
+var errMissingCredentials = errors.New("missing credentials")
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string         // Name of the API key or sub claim of the JWT
+	Method  string         // Mode that authenticated the caller: api_key or jwt
+	Claims  map[string]any // All claims of the JWT, nil for API keys
+}
+
+// principalKey is the context key for the Principal of a call.
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal attached by the interceptor of
+// NewInterceptor. There is none for exempt procedures or without authentication.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// Authenticator checks the credentials in the headers of a request.
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials in header. It
+	// returns nil and no error if requests are not authenticated at all.
+	Authenticate(header http.Header) (*Principal, error)
+}
+
+// New returns the Authenticator of cfg.Mode, loading its credentials from
+// the configured files.
+func New(cfg config.AuthConfig) (Authenticator, error) {
+	switch cfg.Mode {
+	case config.AuthModeAPIKey:
+		keys, err := LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		return keys, nil
+	case config.AuthModeJWT:
+		jwks, err := LoadJWKS(cfg.JWKSFile, cfg.Issuer, cfg.Audience)
+		if err != nil {
+			return nil, err
+		}
+		return jwks, nil
+	default:
+		return noop{}, nil
+	}
+}
+
+// noop accepts every request without a principal.
+type noop struct{}
+
+// Authenticate implements Authenticator.
+func (noop) Authenticate(http.Header) (*Principal, error) {
+	return nil, nil
+}
+
+// bearerToken returns the token of a bearer Authorization header.
+func bearerToken(header http.Header) (string, bool) {
+	scheme, token, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// interceptor authenticates every call that is not exempt.
+type interceptor struct {
+	authenticator Authenticator
+	exempt        []string
+}
+
+// NewInterceptor returns a Connect interceptor authenticating unary and
+// streaming calls with authenticator. Calls without valid credentials fail with
+// CodeUnauthenticated before reaching the handler. Procedures listed in exempt,
+// or belonging to a service listed with a trailing slash, are not authenticated.
+func NewInterceptor(authenticator Authenticator, exempt []string) connect.Interceptor {
+	return interceptor{authenticator: authenticator, exempt: exempt}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := i.authenticate(ctx, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authenticate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authenticate returns ctx with the principal of the credentials in header,
+// or the error reported to the client.
+func (i interceptor) authenticate(ctx context.Context, procedure string, header http.Header) (context.Context, error) {
+	if IsExempt(procedure, i.exempt) {
+		return ctx, nil
+	}
+
+	principal, err := i.authenticator.Authenticate(header)
+	if err != nil {
+		connectErr := rpcerror.New(connect.CodeUnauthenticated, basicerrors.ReasonUnauthenticated, err)
+		connectErr.Meta().Set("WWW-Authenticate", "Bearer")
+		return ctx, connectErr
+	}
+	if principal == nil {
+		return ctx, nil
+	}
+
+	ctx = logging.WithAttrs(ctx, slog.String("principal", principal.Subject))
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// IsExempt reports whether procedure is listed in exempt, either itself or by
+// its service with a trailing slash.
+func IsExempt(procedure string, exempt []string) bool {
+	for _, e := range exempt {
+		if procedure == e || (strings.HasSuffix(e, "/") && strings.HasPrefix(procedure, e)) {
+			return true
+		}
+	}
+	return false
+}
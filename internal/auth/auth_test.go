@@ -0,0 +1,302 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// writeFile writes content to a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// header returns a request header with the given key and value pairs.
+func header(pairs ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i < len(pairs); i += 2 {
+		h.Set(pairs[i], pairs[i+1])
+	}
+	return h
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	keys, err := auth.LoadAPIKeys(writeFile(t, "keys.yaml", "ci-pipeline: secret-1\ndashboard: secret-2\n"))
+	require.NoError(t, err)
+
+	t.Run("should accept keys sent as bearer token", func(t *testing.T) {
+		principal, err := keys.Authenticate(header("Authorization", "Bearer secret-2"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "dashboard", principal.Subject)
+		assert.Equal(t, config.AuthModeAPIKey, principal.Method)
+	})
+
+	t.Run("should accept keys sent in the API key header", func(t *testing.T) {
+		principal, err := keys.Authenticate(header(auth.APIKeyHeader, "secret-1"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "ci-pipeline", principal.Subject)
+	})
+
+	t.Run("should reject unknown and missing keys", func(t *testing.T) {
+		_, err := keys.Authenticate(header("Authorization", "Bearer secret-3"))
+		assert.ErrorContains(t, err, "invalid API key")
+
+		_, err = keys.Authenticate(header("Authorization", "Basic c2VjcmV0LTE="))
+		assert.ErrorContains(t, err, "missing credentials")
+	})
+
+	t.Run("should reject files without keys", func(t *testing.T) {
+		_, err := auth.LoadAPIKeys(writeFile(t, "keys.yaml", "{}"))
+		assert.ErrorContains(t, err, "no keys defined")
+
+		_, err = auth.LoadAPIKeys(writeFile(t, "keys.yaml", "ci-pipeline: ''"))
+		assert.ErrorContains(t, err, "empty key")
+	})
+}
+
+func TestJWT(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: rsaKey.Public(), KeyID: "rsa", Algorithm: string(jose.RS256), Use: "sig"},
+		{Key: hmacKey, KeyID: "hmac", Algorithm: string(jose.HS256), Use: "sig"},
+	}})
+	require.NoError(t, err)
+	verifier, err := auth.LoadJWKS(writeFile(t, "jwks.json", string(jwks)), "https://issuer.example", "basic-service")
+	require.NoError(t, err)
+
+	// sign returns a bearer header with claims signed by key.
+	sign := func(t *testing.T, alg jose.SignatureAlgorithm, kid string, key any, claims jwt.Claims) http.Header {
+		t.Helper()
+
+		options := (&jose.SignerOptions{}).WithType("JWT")
+		if kid != "" {
+			options = options.WithHeader(jose.HeaderKey("kid"), kid)
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, options)
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(claims).Claims(map[string]any{"scope": "basic:read"}).Serialize()
+		require.NoError(t, err)
+		return header("Authorization", "Bearer "+token)
+	}
+	valid := func() jwt.Claims {
+		return jwt.Claims{
+			Subject:  "user-1",
+			Issuer:   "https://issuer.example",
+			Audience: jwt.Audience{"basic-service"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	t.Run("should accept tokens signed with RSA", func(t *testing.T) {
+		principal, err := verifier.Authenticate(sign(t, jose.RS256, "rsa", rsaKey, valid()))
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", principal.Subject)
+		assert.Equal(t, config.AuthModeJWT, principal.Method)
+		assert.Equal(t, "basic:read", principal.Claims["scope"])
+	})
+
+	t.Run("should accept tokens signed with HMAC", func(t *testing.T) {
+		principal, err := verifier.Authenticate(sign(t, jose.HS256, "hmac", hmacKey, valid()))
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", principal.Subject)
+	})
+
+	t.Run("should reject invalid claims", func(t *testing.T) {
+		expired := valid()
+		expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		otherIssuer := valid()
+		otherIssuer.Issuer = "https://attacker.example"
+		otherAudience := valid()
+		otherAudience.Audience = jwt.Audience{"other-service"}
+		noExpiry := valid()
+		noExpiry.Expiry = nil
+		noSubject := valid()
+		noSubject.Subject = ""
+
+		for name, claims := range map[string]jwt.Claims{
+			"expired":        expired,
+			"other issuer":   otherIssuer,
+			"other audience": otherAudience,
+			"no expiry":      noExpiry,
+			"no subject":     noSubject,
+		} {
+			_, err := verifier.Authenticate(sign(t, jose.RS256, "rsa", rsaKey, claims))
+			assert.ErrorContains(t, err, "invalid token", name)
+		}
+	})
+
+	t.Run("should reject tokens not signed by a key of the set", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		_, err = verifier.Authenticate(sign(t, jose.RS256, "rsa", otherKey, valid()))
+		assert.ErrorContains(t, err, "invalid token")
+
+		_, err = verifier.Authenticate(sign(t, jose.RS256, "unknown", rsaKey, valid()))
+		assert.ErrorContains(t, err, "unknown key ID")
+
+		_, err = verifier.Authenticate(sign(t, jose.RS256, "", rsaKey, valid()))
+		assert.ErrorContains(t, err, "missing key ID")
+	})
+
+	t.Run("should reject algorithms not allowed for the key", func(t *testing.T) {
+		_, err := verifier.Authenticate(sign(t, jose.HS256, "rsa", hmacKey, valid()))
+
+		assert.ErrorContains(t, err, "algorithm HS256 not allowed")
+	})
+
+	t.Run("should reject missing tokens", func(t *testing.T) {
+		_, err := verifier.Authenticate(header())
+
+		assert.ErrorContains(t, err, "missing credentials")
+	})
+}
+
+func TestNewInterceptor(t *testing.T) {
+	t.Parallel()
+
+	keys, err := auth.LoadAPIKeys(writeFile(t, "keys.yaml", "ci-pipeline: secret-1\n"))
+	require.NoError(t, err)
+
+	// newServer serves a unary and a streaming procedure returning the subject
+	// of the principal, and an exempt health procedure.
+	newServer := func(authenticator auth.Authenticator) *httptest.Server {
+		subject := func(ctx context.Context) *wrapperspb.StringValue {
+			if principal, ok := auth.PrincipalFromContext(ctx); ok {
+				return wrapperspb.String(principal.Subject)
+			}
+			return wrapperspb.String("")
+		}
+
+		interceptor := connect.WithInterceptors(auth.NewInterceptor(authenticator, config.Default().Auth.Exempt))
+		mux := http.NewServeMux()
+		mux.Handle("/test.v1.TestService/Unary", connect.NewUnaryHandler(
+			"/test.v1.TestService/Unary",
+			func(ctx context.Context, _ *connect.Request[emptypb.Empty]) (*connect.Response[wrapperspb.StringValue], error) {
+				return connect.NewResponse(subject(ctx)), nil
+			},
+			interceptor,
+		))
+		mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+			"/test.v1.TestService/Stream",
+			func(ctx context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[wrapperspb.StringValue]) error {
+				return stream.Send(subject(ctx))
+			},
+			interceptor,
+		))
+		mux.Handle("/grpc.health.v1.Health/Check", connect.NewUnaryHandler(
+			"/grpc.health.v1.Health/Check",
+			func(ctx context.Context, _ *connect.Request[emptypb.Empty]) (*connect.Response[wrapperspb.StringValue], error) {
+				return connect.NewResponse(subject(ctx)), nil
+			},
+			interceptor,
+		))
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	// unary calls procedure on server with the given API key, empty sends none.
+	unary := func(server *httptest.Server, procedure, key string) (string, error) {
+		client := connect.NewClient[emptypb.Empty, wrapperspb.StringValue](server.Client(), server.URL+procedure)
+		req := connect.NewRequest(&emptypb.Empty{})
+		if key != "" {
+			req.Header().Set("Authorization", "Bearer "+key)
+		}
+		resp, err := client.CallUnary(context.Background(), req)
+		if err != nil {
+			return "", err
+		}
+		return resp.Msg.Value, nil
+	}
+
+	// stream calls the streaming procedure on server with the given API key.
+	stream := func(server *httptest.Server, key string) (string, error) {
+		client := connect.NewClient[emptypb.Empty, wrapperspb.StringValue](server.Client(), server.URL+"/test.v1.TestService/Stream")
+		req := connect.NewRequest(&emptypb.Empty{})
+		if key != "" {
+			req.Header().Set("Authorization", "Bearer "+key)
+		}
+		stream, err := client.CallServerStream(context.Background(), req)
+		if err != nil {
+			return "", err
+		}
+		defer stream.Close()
+		if !stream.Receive() {
+			return "", stream.Err()
+		}
+		return stream.Msg().Value, nil
+	}
+
+	server := newServer(keys)
+
+	t.Run("should attach the principal to unary and streaming calls", func(t *testing.T) {
+		subject, err := unary(server, "/test.v1.TestService/Unary", "secret-1")
+		require.NoError(t, err)
+		assert.Equal(t, "ci-pipeline", subject)
+
+		subject, err = stream(server, "secret-1")
+		require.NoError(t, err)
+		assert.Equal(t, "ci-pipeline", subject)
+	})
+
+	t.Run("should reject unary and streaming calls without valid credentials", func(t *testing.T) {
+		_, err := unary(server, "/test.v1.TestService/Unary", "")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+
+		_, err = unary(server, "/test.v1.TestService/Unary", "wrong")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+
+		_, err = stream(server, "wrong")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("should not authenticate exempt procedures", func(t *testing.T) {
+		subject, err := unary(server, "/grpc.health.v1.Health/Check", "")
+
+		require.NoError(t, err)
+		assert.Empty(t, subject)
+	})
+
+	t.Run("should accept every call without authentication", func(t *testing.T) {
+		authenticator, err := auth.New(config.Default().Auth)
+		require.NoError(t, err)
+		server := newServer(authenticator)
+
+		subject, err := unary(server, "/test.v1.TestService/Unary", "")
+		require.NoError(t, err)
+		assert.Empty(t, subject)
+	})
+}
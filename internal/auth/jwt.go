@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+)
+
+// signatureAlgorithms are the HMAC and RSA algorithms accepted for JWTs. A key
+// only verifies algorithms of its own type, so a token cannot switch from RSA
+// to HMAC to have the public key used as shared secret.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.HS256, jose.HS384, jose.HS512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+}
+
+// JWT authenticates requests by bearer JWTs signed with a key of a JSON Web Key
+// Set. Tokens must not be expired, must have a subject and, if configured, the
+// expected issuer and audience.
+type JWT struct {
+	keys     jose.JSONWebKeySet
+	issuer   string
+	audience string
+}
+
+// LoadJWKS reads the JSON Web Key Set at path. HMAC keys (kty oct) and RSA
+// keys are supported, RSA private keys are reduced to their public part.
+// Empty issuer or audience accept any value of the claim.
+func LoadJWKS(path, issuer, audience string) (*JWT, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS: %w", err)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parse JWKS %s: %w", path, err)
+	}
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("parse JWKS %s: no keys defined", path)
+	}
+	for i, key := range keys.Keys {
+		if _, symmetric := key.Key.([]byte); !symmetric && !key.IsPublic() {
+			keys.Keys[i] = key.Public()
+		}
+	}
+
+	return &JWT{keys: keys, issuer: issuer, audience: audience}, nil
+}
+
+// Authenticate implements Authenticator.
+func (j *JWT) Authenticate(header http.Header) (*Principal, error) {
+	token, ok := bearerToken(header)
+	if !ok {
+		return nil, errMissingCredentials
+	}
+
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	key, err := j.key(parsed.Headers[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	var claims jwt.Claims
+	var all map[string]any
+	if err := parsed.Claims(key.Key, &claims, &all); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	expected := jwt.Expected{Issuer: j.issuer, Time: time.Now()}
+	if j.audience != "" {
+		expected.AnyAudience = jwt.Audience{j.audience}
+	}
+	if err := claims.Validate(expected); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Expiry == nil {
+		return nil, errors.New("invalid token: missing exp claim")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: missing sub claim")
+	}
+
+	return &Principal{Subject: claims.Subject, Method: config.AuthModeJWT, Claims: all}, nil
+}
+
+// key returns the key verifying a token with header. Tokens without key ID
+// are accepted if the set holds a single key.
+func (j *JWT) key(header jose.Header) (jose.JSONWebKey, error) {
+	var key jose.JSONWebKey
+	switch {
+	case header.KeyID != "":
+		keys := j.keys.Key(header.KeyID)
+		if len(keys) == 0 {
+			return key, fmt.Errorf("unknown key ID %q", header.KeyID)
+		}
+		key = keys[0]
+	case len(j.keys.Keys) == 1:
+		key = j.keys.Keys[0]
+	default:
+		return key, errors.New("missing key ID")
+	}
+
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return key, fmt.Errorf("algorithm %s not allowed for key %q", header.Algorithm, key.KeyID)
+	}
+	return key, nil
+}
@@ -0,0 +1,253 @@
+// Package authz authorizes authenticated callers per procedure. A declarative
+// policy file maps principals, roles and scopes to the procedures they may
+// call, and an interceptor enforces it after authentication.
+package authz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"gopkg.in/yaml.v3"
+)
+
+// Default decisions for procedures without a matching rule.
+const (
+	DefaultAllow = "allow"
+	DefaultDeny  = "deny"
+)
+
+// Rule grants a procedure, a service or everything to callers matching any of
+// its principals, roles or scopes.
+type Rule struct {
+	Procedure  string   `yaml:"procedure"`  // Full procedure name, a service ending in / or / for every procedure
+	Principals []string `yaml:"principals"` // Subjects allowed to call, * allows every authenticated caller
+	Roles      []string `yaml:"roles"`      // Roles allowed to call, from the roles claim or the roles of the policy
+	Scopes     []string `yaml:"scopes"`     // Scopes allowed to call, from the scope or scp claim
+}
+
+// Policy decides which principals may call which procedures. The most specific
+// rule matching a procedure applies: a full procedure name before its service
+// before /. Procedures without a matching rule are denied unless Default is
+// allow.
+type Policy struct {
+	Default string              `yaml:"default"` // Decision without a matching rule: allow or deny, empty denies
+	Roles   map[string][]string `yaml:"roles"`   // Role name to the subjects holding it, for API keys and JWTs without roles claim
+	Rules   []Rule              `yaml:"rules"`
+}
+
+// LoadPolicy reads the policy from the YAML file at path:
+//
+//	roles:
+//	  operator: [ci-pipeline]
+//	rules:
+//	  - procedure: /basic.v1.BasicService/Background
+//	    roles: [operator]
+//	    scopes: [basic:background]
+//	  - procedure: /basic.v1.BasicService/
+//	    principals: ["*"]
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// validate rejects policies whose rules could never grant anything or would be
+// ambiguous.
+func (p *Policy) validate() error {
+	if p.Default != "" && p.Default != DefaultAllow && p.Default != DefaultDeny {
+		return fmt.Errorf("default must be %s or %s, got %q", DefaultAllow, DefaultDeny, p.Default)
+	}
+	seen := map[string]bool{}
+	for i, rule := range p.Rules {
+		switch {
+		case !strings.HasPrefix(rule.Procedure, "/"):
+			return fmt.Errorf("rule %d: procedure must start with /, got %q", i+1, rule.Procedure)
+		case seen[rule.Procedure]:
+			return fmt.Errorf("rule %d: duplicate rule for %s", i+1, rule.Procedure)
+		case len(rule.Principals) == 0 && len(rule.Roles) == 0 && len(rule.Scopes) == 0:
+			return fmt.Errorf("rule %d: %s must list principals, roles or scopes", i+1, rule.Procedure)
+		}
+		seen[rule.Procedure] = true
+	}
+	return nil
+}
+
+// Authorize returns nil if principal may call procedure, or an error with the
+// reason otherwise. A nil principal is an unauthenticated caller.
+func (p *Policy) Authorize(procedure string, principal *auth.Principal) error {
+	rule := p.rule(procedure)
+	if rule == nil {
+		if p.Default == DefaultAllow {
+			return nil
+		}
+		return fmt.Errorf("no policy rule allows %s", procedure)
+	}
+	if principal == nil {
+		return fmt.Errorf("unauthenticated callers may not call %s", procedure)
+	}
+
+	if slices.Contains(rule.Principals, "*") || slices.Contains(rule.Principals, principal.Subject) {
+		return nil
+	}
+	for _, role := range p.roles(principal) {
+		if slices.Contains(rule.Roles, role) {
+			return nil
+		}
+	}
+	for _, scope := range scopes(principal) {
+		if slices.Contains(rule.Scopes, scope) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s may not call %s, requires %s", principal.Subject, procedure, rule.requirement())
+}
+
+// rule returns the most specific rule matching procedure, nil if none does.
+func (p *Policy) rule(procedure string) *Rule {
+	var match *Rule
+	for i, rule := range p.Rules {
+		if rule.Procedure != procedure && !(strings.HasSuffix(rule.Procedure, "/") && strings.HasPrefix(procedure, rule.Procedure)) {
+			continue
+		}
+		if match == nil || len(rule.Procedure) > len(match.Procedure) {
+			match = &p.Rules[i]
+		}
+	}
+	return match
+}
+
+// roles returns the roles of principal, from its roles claim and the roles of
+// the policy.
+func (p *Policy) roles(principal *auth.Principal) []string {
+	roles := claimValues(principal.Claims["roles"])
+	for role, subjects := range p.Roles {
+		if slices.Contains(subjects, principal.Subject) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// scopes returns the scopes of principal, from the space separated scope claim
+// or the scp claim.
+func scopes(principal *auth.Principal) []string {
+	return append(claimValues(principal.Claims["scope"]), claimValues(principal.Claims["scp"])...)
+}
+
+// claimValues returns the strings of a claim holding a space separated string
+// or an array of strings.
+func claimValues(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		var values []string
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// requirement describes what a rule accepts, for denial reasons.
+func (r *Rule) requirement() string {
+	var options []string
+	for _, option := range []struct {
+		kind   string
+		values []string
+	}{{"principal", r.Principals}, {"role", r.Roles}, {"scope", r.Scopes}} {
+		if len(option.values) > 0 {
+			options = append(options, option.kind+" "+strings.Join(option.values, ", "))
+		}
+	}
+	return strings.Join(options, " or ")
+}
+
+// interceptor enforces a policy on every call that is not exempt.
+type interceptor struct {
+	policy *Policy
+	dryRun bool
+	exempt []string
+}
+
+// NewInterceptor returns a Connect interceptor enforcing policy on unary and
+// streaming calls. It must run after the interceptor of auth.NewInterceptor.
+// Denied calls fail with CodePermissionDenied and the reason, or in dryRun mode
+// are only logged. Exempt procedures and a nil policy allow every call.
+func NewInterceptor(policy *Policy, dryRun bool, exempt []string) connect.Interceptor {
+	return interceptor{policy: policy, dryRun: dryRun, exempt: exempt}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.authorize(ctx, req.Spec().Procedure); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := i.authorize(ctx, conn.Spec().Procedure); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authorize returns the error reported to the client if the principal of ctx
+// may not call procedure.
+func (i interceptor) authorize(ctx context.Context, procedure string) error {
+	if i.policy == nil || auth.IsExempt(procedure, i.exempt) {
+		return nil
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	err := i.policy.Authorize(procedure, principal)
+	if err == nil {
+		return nil
+	}
+	if i.dryRun {
+		slog.WarnContext(ctx, "Call denied by authorization policy, allowed in dry run", "reason", err.Error())
+		return nil
+	}
+	return rpcerror.New(connect.CodePermissionDenied, basicerrors.ReasonPermissionDenied, err, "procedure", procedure)
+}
@@ -0,0 +1,213 @@
+package authz_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/authz"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const policyYAML = `
+roles:
+  operator: [ci-pipeline]
+rules:
+  - procedure: /basic.v1.BasicService/Background
+    roles: [operator]
+    scopes: [basic:background]
+  - procedure: /basic.v1.BasicService/
+    principals: ["*"]
+`
+
+// loadPolicy writes content to a policy file and loads it.
+func loadPolicy(t *testing.T, content string) (*authz.Policy, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return authz.LoadPolicy(path)
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := loadPolicy(t, policyYAML)
+	require.NoError(t, err)
+
+	t.Run("should allow every principal matched by a service rule", func(t *testing.T) {
+		err := policy.Authorize(basicV1connect.BasicServiceHelloProcedure, &auth.Principal{Subject: "dashboard"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should apply the most specific rule", func(t *testing.T) {
+		err := policy.Authorize(basicV1connect.BasicServiceBackgroundProcedure, &auth.Principal{Subject: "dashboard"})
+
+		assert.EqualError(t, err, "dashboard may not call /basic.v1.BasicService/Background, requires role operator or scope basic:background")
+	})
+
+	t.Run("should allow roles of the policy and of the roles claim", func(t *testing.T) {
+		assert.NoError(t, policy.Authorize(basicV1connect.BasicServiceBackgroundProcedure, &auth.Principal{Subject: "ci-pipeline"}))
+		assert.NoError(t, policy.Authorize(basicV1connect.BasicServiceBackgroundProcedure, &auth.Principal{
+			Subject: "user-1",
+			Claims:  map[string]any{"roles": []any{"viewer", "operator"}},
+		}))
+	})
+
+	t.Run("should allow scopes of the scope and scp claims", func(t *testing.T) {
+		assert.NoError(t, policy.Authorize(basicV1connect.BasicServiceBackgroundProcedure, &auth.Principal{
+			Subject: "user-1",
+			Claims:  map[string]any{"scope": "basic:read basic:background"},
+		}))
+		assert.NoError(t, policy.Authorize(basicV1connect.BasicServiceBackgroundProcedure, &auth.Principal{
+			Subject: "user-1",
+			Claims:  map[string]any{"scp": []any{"basic:background"}},
+		}))
+	})
+
+	t.Run("should deny procedures without rule unless the default allows them", func(t *testing.T) {
+		assert.EqualError(t, policy.Authorize("/other.v1.OtherService/Call", &auth.Principal{Subject: "ci-pipeline"}),
+			"no policy rule allows /other.v1.OtherService/Call")
+
+		allowing, err := loadPolicy(t, "default: allow\n"+policyYAML)
+		require.NoError(t, err)
+		assert.NoError(t, allowing.Authorize("/other.v1.OtherService/Call", &auth.Principal{Subject: "ci-pipeline"}))
+	})
+
+	t.Run("should deny unauthenticated callers", func(t *testing.T) {
+		err := policy.Authorize(basicV1connect.BasicServiceHelloProcedure, nil)
+
+		assert.EqualError(t, err, "unauthenticated callers may not call /basic.v1.BasicService/Hello")
+	})
+
+	t.Run("should reject invalid policies", func(t *testing.T) {
+		for content, message := range map[string]string{
+			"default: maybe": "default must be allow or deny",
+			"rules: [{procedure: basic.v1.BasicService/, principals: ['*']}]":     "procedure must start with /",
+			"rules: [{procedure: /basic.v1.BasicService/}]":                       "must list principals, roles or scopes",
+			"rules: [{procedure: /a/, roles: [x]}, {procedure: /a/, roles: [y]}]": "duplicate rule for /a/",
+			"rule: []": "field rule not found",
+		} {
+			_, err := loadPolicy(t, content)
+			assert.ErrorContains(t, err, message, content)
+		}
+	})
+}
+
+// lockedBuffer is a bytes.Buffer written by the server and read by the test.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNewInterceptor(t *testing.T) {
+	var logs lockedBuffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &logs))
+	defer slog.SetDefault(previous)
+
+	policy, err := loadPolicy(t, policyYAML)
+	require.NoError(t, err)
+	keysFile := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(keysFile, []byte("ci-pipeline: secret-1\ndashboard: secret-2\n"), 0o600))
+	keys, err := auth.LoadAPIKeys(keysFile)
+	require.NoError(t, err)
+
+	// newServer serves the Background procedure as unary and the Talk procedure
+	// as streaming handler behind authentication and the policy interceptor.
+	newServer := func(dryRun bool) *httptest.Server {
+		exempt := config.Default().Auth.Exempt
+		interceptors := connect.WithInterceptors(auth.NewInterceptor(keys, exempt), authz.NewInterceptor(policy, dryRun, exempt))
+		mux := http.NewServeMux()
+		mux.Handle(basicV1connect.BasicServiceBackgroundProcedure, connect.NewUnaryHandler(
+			basicV1connect.BasicServiceBackgroundProcedure,
+			func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			},
+			interceptors,
+		))
+		mux.Handle(basicV1connect.BasicServiceTalkProcedure, connect.NewServerStreamHandler(
+			basicV1connect.BasicServiceTalkProcedure,
+			func(_ context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+				return stream.Send(&emptypb.Empty{})
+			},
+			interceptors,
+		))
+		server := httptest.NewServer(logging.Handler(mux))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	// unary calls Background on server with the given API key.
+	unary := func(server *httptest.Server, key string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+basicV1connect.BasicServiceBackgroundProcedure)
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set(auth.APIKeyHeader, key)
+		_, err := client.CallUnary(context.Background(), req)
+		return err
+	}
+
+	// stream calls Talk on server with the given API key.
+	stream := func(server *httptest.Server, key string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+basicV1connect.BasicServiceTalkProcedure)
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set(auth.APIKeyHeader, key)
+		stream, err := client.CallServerStream(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+		for stream.Receive() {
+		}
+		return stream.Err()
+	}
+
+	enforcing := newServer(false)
+
+	t.Run("should deny calls not allowed by the policy with the reason", func(t *testing.T) {
+		err := unary(enforcing, "secret-2")
+
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "dashboard may not call /basic.v1.BasicService/Background")
+	})
+
+	t.Run("should allow calls allowed by the policy", func(t *testing.T) {
+		assert.NoError(t, unary(enforcing, "secret-1"))
+		assert.NoError(t, stream(enforcing, "secret-2"))
+	})
+
+	t.Run("should only log denied calls in dry run", func(t *testing.T) {
+		err := unary(newServer(true), "secret-2")
+
+		require.NoError(t, err)
+		assert.Contains(t, logs.String(), `"msg":"Call denied by authorization policy, allowed in dry run"`)
+		assert.Contains(t, logs.String(), `"reason":"dashboard may not call /basic.v1.BasicService/Background, requires role operator or scope basic:background"`)
+		assert.Contains(t, logs.String(), `"principal":"dashboard"`)
+	})
+}
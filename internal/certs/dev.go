@@ -0,0 +1,148 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// devValidity is how long generated development certificates remain valid.
+const devValidity = 7 * 24 * time.Hour
+
+// DevelopmentCertificates holds an ephemeral CA and a server certificate signed by it.
+// They only live in memory unless the CA is written to disk explicitly.
+type DevelopmentCertificates struct {
+	CA    *x509.Certificate // Self-signed certificate authority
+	CAPEM []byte            // PEM encoding of CA for clients that need to trust it
+	Leaf  tls.Certificate   // Server certificate covering the requested hosts
+}
+
+// GenerateDevelopment creates a fresh CA and a server certificate valid for the
+// host of the bind address addr as well as localhost, 127.0.0.1 and ::1.
+func GenerateDevelopment(addr string) (*DevelopmentCertificates, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "basic-grpc-service development CA", Organization: []string{"basic-grpc-service"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(devValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create development CA: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: "localhost", Organization: []string{"basic-grpc-service"}},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(devValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range developmentHosts(addr) {
+		if ip := net.ParseIP(host); ip != nil {
+			leafTemplate.IPAddresses = append(leafTemplate.IPAddresses, ip)
+		} else {
+			leafTemplate.DNSNames = append(leafTemplate.DNSNames, host)
+		}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create development certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DevelopmentCertificates{
+		CA:    ca,
+		CAPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Leaf: tls.Certificate{
+			Certificate: [][]byte{leafDER, caDER},
+			PrivateKey:  leafKey,
+			Leaf:        leaf,
+		},
+	}, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the CA in the colon separated
+// hex notation shown by browsers and openssl.
+func (d *DevelopmentCertificates) Fingerprint() string {
+	sum := sha256.Sum256(d.CA.Raw)
+	encoded := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(encoded); i += 2 {
+		pairs = append(pairs, encoded[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// WriteCA stores the PEM encoded CA at path, creating parent directories as needed.
+func (d *DevelopmentCertificates) WriteCA(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, d.CAPEM, 0o644) //nolint:gosec
+}
+
+// developmentHosts returns the host names and addresses a development
+// certificate for the bind address addr has to cover.
+func developmentHosts(addr string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return hosts
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return hosts
+	}
+	for _, known := range hosts {
+		if strings.EqualFold(host, known) {
+			return hosts
+		}
+	}
+	return append(hosts, host)
+}
+
+// serialNumber returns a random 128 bit certificate serial number.
+func serialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
@@ -0,0 +1,77 @@
+package certs_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/certs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDevelopment(t *testing.T) {
+	t.Parallel()
+
+	// verify checks that the leaf certificate is trusted for host when the CA is trusted.
+	verify := func(dev *certs.DevelopmentCertificates, host string) error {
+		roots := x509.NewCertPool()
+		roots.AddCert(dev.CA)
+		_, err := dev.Leaf.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		return err
+	}
+
+	t.Run("should cover localhost and the loopback addresses", func(t *testing.T) {
+		dev, err := certs.GenerateDevelopment("0.0.0.0:8443")
+		require.NoError(t, err)
+
+		for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+			assert.NoError(t, verify(dev, host), host)
+		}
+		assert.Error(t, verify(dev, "example.com"))
+	})
+
+	t.Run("should cover the host of the bind address", func(t *testing.T) {
+		dev, err := certs.GenerateDevelopment("grpc.internal:8443")
+		require.NoError(t, err)
+		assert.NoError(t, verify(dev, "grpc.internal"))
+
+		dev, err = certs.GenerateDevelopment("10.1.2.3:8443")
+		require.NoError(t, err)
+		assert.NoError(t, verify(dev, "10.1.2.3"))
+	})
+
+	t.Run("should generate a new CA every time", func(t *testing.T) {
+		first, err := certs.GenerateDevelopment("127.0.0.1:8443")
+		require.NoError(t, err)
+		second, err := certs.GenerateDevelopment("127.0.0.1:8443")
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first.Fingerprint(), second.Fingerprint())
+		assert.Error(t, verify(&certs.DevelopmentCertificates{CA: first.CA, Leaf: second.Leaf}, "localhost"))
+	})
+
+	t.Run("should format the fingerprint as colon separated SHA-256", func(t *testing.T) {
+		dev, err := certs.GenerateDevelopment("127.0.0.1:8443")
+		require.NoError(t, err)
+
+		assert.Regexp(t, regexp.MustCompile(`^([0-9A-F]{2}:){31}[0-9A-F]{2}$`), dev.Fingerprint())
+	})
+
+	t.Run("should write the CA as PEM", func(t *testing.T) {
+		dev, err := certs.GenerateDevelopment("127.0.0.1:8443")
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "certs", "dev-ca.crt")
+		require.NoError(t, dev.WriteCA(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		block, _ := pem.Decode(data)
+		require.NotNil(t, block)
+		assert.Equal(t, dev.CA.Raw, block.Bytes)
+	})
+}
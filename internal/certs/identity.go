@@ -0,0 +1,64 @@
+package certs
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// clientIdentityKey is the context key under which the ClientIdentity is stored.
+type clientIdentityKey struct{}
+
+// ClientIdentity describes the subject of a verified client certificate.
+type ClientIdentity struct {
+	Subject        string     // Distinguished name of the certificate subject
+	CommonName     string     // Common name of the certificate subject
+	DNSNames       []string   // DNS subject alternative names
+	EmailAddresses []string   // Email subject alternative names
+	IPAddresses    []string   // IP subject alternative names
+	URIs           []*url.URL // URI subject alternative names, e.g. SPIFFE IDs
+}
+
+// String returns the subject of the identity for use in audit logs.
+func (i *ClientIdentity) String() string {
+	return i.Subject
+}
+
+// ClientIdentityFromContext returns the verified client identity of the current
+// call. The second return value is false when the client did not present a
+// certificate or mutual TLS is disabled.
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return identity, ok
+}
+
+// WithClientIdentity returns a copy of ctx carrying identity.
+func WithClientIdentity(ctx context.Context, identity *ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// ClientIdentityHandler wraps next so that requests authenticated with a verified
+// client certificate carry the certificate's identity in their context. It works
+// for both HTTP/2 and HTTP/3 requests since both expose the TLS connection state.
+func ClientIdentityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leaf := r.TLS.VerifiedChains[0][0]
+		identity := &ClientIdentity{
+			Subject:        leaf.Subject.String(),
+			CommonName:     leaf.Subject.CommonName,
+			DNSNames:       leaf.DNSNames,
+			EmailAddresses: leaf.EmailAddresses,
+			URIs:           leaf.URIs,
+		}
+		for _, ip := range leaf.IPAddresses {
+			identity.IPAddresses = append(identity.IPAddresses, ip.String())
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClientIdentity(r.Context(), identity)))
+	})
+}
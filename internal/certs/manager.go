@@ -0,0 +1,221 @@
+package certs
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+)
+
+// reloadMetrics counts certificate reload outcomes by "success" and "failure".
+var reloadMetrics = expvar.NewMap("certificate_reloads")
+
+// certificateSet is an immutable snapshot of the certificates served by a Manager.
+type certificateSet struct {
+	fallback *tls.Certificate   // Served when no SNI certificate matches
+	sni      []*tls.Certificate // Selected by matching the ClientHello server name
+}
+
+// Manager serves TLS certificates through tls.Config.GetCertificate and reloads
+// them from disk when the files change, so certificates can be rotated without
+// restarting the process or dropping connections.
+type Manager struct {
+	certFile string
+	keyFile  string
+	sniDir   string
+	interval time.Duration
+
+	current atomic.Pointer[certificateSet]
+
+	mu      sync.Mutex // Serializes reloads
+	version [sha256.Size]byte
+}
+
+// NewManager creates a Manager for the certificate, key and optional SNI
+// directory configured in cfg. The certificates are loaded immediately; an error
+// is returned if they cannot be loaded.
+func NewManager(cfg config.TLSConfig) (*Manager, error) {
+	m := &Manager{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		sniDir:   cfg.SNIDir,
+		interval: cfg.ReloadInterval,
+	}
+
+	if _, err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// NewStaticManager creates a Manager that always serves cert and never reloads,
+// for certificates that only exist in memory such as development certificates.
+func NewStaticManager(cert tls.Certificate) *Manager {
+	m := &Manager{}
+	m.current.Store(&certificateSet{fallback: &cert})
+	return m
+}
+
+// GetCertificate returns the certificate for the given ClientHello. SNI
+// certificates whose names match the requested server name take precedence over
+// the default certificate. It is safe to call concurrently with Reload.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := m.current.Load()
+	if hello.ServerName != "" {
+		for _, cert := range set.sni {
+			if cert.Leaf.VerifyHostname(hello.ServerName) == nil {
+				return cert, nil
+			}
+		}
+	}
+	return set.fallback, nil
+}
+
+// Reload loads the certificates again if any of the watched files changed. The
+// served certificates are replaced atomically and only if all of them could be
+// loaded, otherwise the previous certificates stay in place. Returns whether
+// new certificates were installed. Static managers never reload.
+func (m *Manager) Reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.certFile == "" {
+		return false, nil
+	}
+
+	version, err := m.fingerprint()
+	if err != nil {
+		return false, m.failed(err)
+	}
+	if m.current.Load() != nil && version == m.version {
+		return false, nil
+	}
+
+	set, err := m.load()
+	if err != nil {
+		return false, m.failed(err)
+	}
+
+	m.current.Store(set)
+	m.version = version
+	reloadMetrics.Add("success", 1)
+	slog.Info("Loaded TLS certificate", "cert_file", m.certFile, "sni_certificates", len(set.sni))
+
+	return true, nil
+}
+
+// Watch checks the certificate files for changes every reload interval until
+// ctx is cancelled. A non-positive interval disables watching.
+func (m *Manager) Watch(ctx context.Context) {
+	if m.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = m.Reload()
+		}
+	}
+}
+
+// failed records and logs a failed reload and returns err wrapped with context.
+func (m *Manager) failed(err error) error {
+	reloadMetrics.Add("failure", 1)
+	err = fmt.Errorf("reload TLS certificates: %w", err)
+	slog.Error("Keeping previous certificates", "error", err)
+	return err
+}
+
+// load reads the default certificate and all SNI certificates from disk.
+func (m *Manager) load() (*certificateSet, error) {
+	fallback, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &certificateSet{fallback: &fallback}
+	pairs, err := m.sniPairs()
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		cert, err := tls.LoadX509KeyPair(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		set.sni = append(set.sni, &cert)
+	}
+
+	return set, nil
+}
+
+// sniPairs returns the certificate and key paths of every "<name>.crt" file in
+// the SNI directory that has a matching "<name>.key" file, sorted by name.
+func (m *Manager) sniPairs() ([][2]string, error) {
+	if m.sniDir == "" {
+		return nil, nil
+	}
+
+	certFiles, err := filepath.Glob(filepath.Join(m.sniDir, "*.crt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(certFiles)
+
+	var pairs [][2]string
+	for _, certFile := range certFiles {
+		keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+		if _, err := os.Stat(keyFile); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		pairs = append(pairs, [2]string{certFile, keyFile})
+	}
+	return pairs, nil
+}
+
+// fingerprint hashes the names and contents of all watched files, so any
+// rotation including symlink swaps and added or removed SNI certificates is noticed.
+func (m *Manager) fingerprint() ([sha256.Size]byte, error) {
+	var version [sha256.Size]byte
+
+	files := []string{m.certFile, m.keyFile}
+	pairs, err := m.sniPairs()
+	if err != nil {
+		return version, err
+	}
+	for _, pair := range pairs {
+		files = append(files, pair[0], pair[1])
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return version, err
+		}
+		fmt.Fprintf(hash, "%s:%d:", file, len(data))
+		hash.Write(data)
+	}
+	copy(version[:], hash.Sum(nil))
+
+	return version, nil
+}
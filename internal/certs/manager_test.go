@@ -0,0 +1,154 @@
+package certs_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/certs"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servedCommonName returns the common name of the certificate the manager serves for serverName.
+func servedCommonName(t *testing.T, manager *certs.Manager, serverName string) string {
+	t.Helper()
+
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	require.NoError(t, err)
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+
+	t.Run("should fail when the certificate is missing", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := certs.NewManager(config.TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: filepath.Join(dir, "missing.key")})
+		assert.ErrorContains(t, err, "reload TLS certificates")
+	})
+
+	t.Run("should serve rotated certificates after reload", func(t *testing.T) {
+		certFile, keyFile := writeKeyPair(t, t.TempDir(), ca.issue(t, "first", x509.ExtKeyUsageServerAuth))
+		manager, err := certs.NewManager(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+		assert.Equal(t, "first", servedCommonName(t, manager, ""))
+
+		reloaded, err := manager.Reload()
+		require.NoError(t, err)
+		assert.False(t, reloaded, "unchanged files must not trigger a reload")
+
+		writeKeyPair(t, filepath.Dir(certFile), ca.issue(t, "second", x509.ExtKeyUsageServerAuth))
+		reloaded, err = manager.Reload()
+		require.NoError(t, err)
+		assert.True(t, reloaded)
+		assert.Equal(t, "second", servedCommonName(t, manager, ""))
+	})
+
+	t.Run("should keep serving the previous certificate when reloading fails", func(t *testing.T) {
+		certFile, keyFile := writeKeyPair(t, t.TempDir(), ca.issue(t, "stable", x509.ExtKeyUsageServerAuth))
+		manager, err := certs.NewManager(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(certFile, []byte("half written"), 0o600))
+		_, err = manager.Reload()
+		assert.Error(t, err)
+		assert.Equal(t, "stable", servedCommonName(t, manager, ""))
+	})
+
+	t.Run("should select SNI certificates by server name", func(t *testing.T) {
+		certFile, keyFile := writeKeyPair(t, t.TempDir(), ca.issue(t, "default.example.com", x509.ExtKeyUsageServerAuth))
+		sniDir := t.TempDir()
+		sniCert, sniKey := writeKeyPair(t, sniDir, ca.issue(t, "api.example.com", x509.ExtKeyUsageServerAuth))
+		require.NoError(t, os.Rename(sniCert, filepath.Join(sniDir, "api.crt")))
+		require.NoError(t, os.Rename(sniKey, filepath.Join(sniDir, "api.key")))
+
+		manager, err := certs.NewManager(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, SNIDir: sniDir})
+		require.NoError(t, err)
+
+		assert.Equal(t, "api.example.com", servedCommonName(t, manager, "api.example.com"))
+		assert.Equal(t, "default.example.com", servedCommonName(t, manager, "other.example.com"))
+		assert.Equal(t, "default.example.com", servedCommonName(t, manager, ""))
+	})
+
+	t.Run("should pick up changes while watching", func(t *testing.T) {
+		certFile, keyFile := writeKeyPair(t, t.TempDir(), ca.issue(t, "before", x509.ExtKeyUsageServerAuth))
+		manager, err := certs.NewManager(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: 10 * time.Millisecond})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			manager.Watch(ctx)
+			close(done)
+		}()
+
+		writeKeyPair(t, filepath.Dir(certFile), ca.issue(t, "after", x509.ExtKeyUsageServerAuth))
+		assert.Eventually(t, func() bool {
+			return servedCommonName(t, manager, "") == "after"
+		}, 2*time.Second, 10*time.Millisecond)
+
+		cancel()
+		<-done
+	})
+}
+
+func TestManagerServesHandshakes(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	ca2 := newTestCA(t)
+	certFile, keyFile := writeKeyPair(t, t.TempDir(), ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth))
+	cfg := config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: certs.ClientAuthNone}
+	manager, err := certs.NewManager(cfg)
+	require.NoError(t, err)
+	tlsConfig, err := certs.NewServerTLSConfig(cfg, manager)
+	require.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	handshake := func(root *testCA) error {
+		pool := x509.NewCertPool()
+		pool.AddCert(root.cert)
+
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", ln.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	require.NoError(t, handshake(ca))
+
+	// Rotate to a certificate issued by a different CA; existing trust must fail
+	// and the new trust must succeed without rebuilding the TLS configuration.
+	writeKeyPair(t, filepath.Dir(certFile), ca2.issue(t, "localhost", x509.ExtKeyUsageServerAuth))
+	_, err = manager.Reload()
+	require.NoError(t, err)
+
+	assert.Error(t, handshake(ca))
+	assert.NoError(t, handshake(ca2))
+}
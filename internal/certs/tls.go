@@ -0,0 +1,74 @@
+// Package certs builds the TLS configuration shared by the HTTP/2 and HTTP/3
+// servers and exposes verified client certificate identities to handlers.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+)
+
+// Client certificate verification modes accepted in config.TLSConfig.ClientAuth.
+const (
+	ClientAuthNone    = "none"    // Client certificates are neither requested nor verified
+	ClientAuthRequest = "request" // Client certificates are requested and verified if presented
+	ClientAuthRequire = "require" // A valid client certificate is mandatory
+)
+
+// NewServerTLSConfig creates the server side TLS configuration from cfg. Server
+// certificates are served by manager so they can be rotated at runtime and,
+// unless client authentication is disabled, client certificates are verified
+// against the configured CA bundle.
+func NewServerTLSConfig(cfg config.TLSConfig, manager *Manager) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: manager.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if err := configureClientAuth(tlsConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	return tlsConfig, nil
+}
+
+// configureClientAuth applies the client certificate verification mode and CA
+// bundle from cfg to tlsConfig.
+func configureClientAuth(tlsConfig *tls.Config, cfg config.TLSConfig) error {
+	switch cfg.ClientAuth {
+	case "", ClientAuthNone:
+		tlsConfig.ClientAuth = tls.NoClientCert
+		return nil
+	case ClientAuthRequest:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return fmt.Errorf("unknown client auth mode %q", cfg.ClientAuth)
+	}
+
+	pool, err := loadCertPool(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("load client CA bundle: %w", err)
+	}
+	tlsConfig.ClientCAs = pool
+
+	return nil
+}
+
+// loadCertPool reads a PEM bundle and returns a pool containing all of its certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
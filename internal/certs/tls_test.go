@@ -0,0 +1,235 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/certs"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a throw-away certificate authority for issuing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA certificate.
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate for the given subject and returns it as a key pair.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Basic"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{commonName},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	cert, err := tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeKeyPair stores cert as PEM files in dir and returns their paths.
+func writeKeyPair(t *testing.T, dir string, cert tls.Certificate) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// newTestServer starts a TLS server that reports the client identity found in
+// the request context.
+func newTestServer(t *testing.T, ca *testCA, clientAuth string) *httptest.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth))
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	cfg := config.TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientAuth:   clientAuth,
+		ClientCAFile: caFile,
+	}
+	manager, err := certs.NewManager(cfg)
+	require.NoError(t, err)
+	tlsConfig, err := certs.NewServerTLSConfig(cfg, manager)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(certs.ClientIdentityHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := certs.ClientIdentityFromContext(r.Context())
+		if !ok {
+			io.WriteString(w, "anonymous")
+			return
+		}
+		io.WriteString(w, identity.CommonName+"|"+identity.Subject+"|"+identity.DNSNames[0]+"|"+identity.IPAddresses[0])
+	})))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// get performs a request against server using the given client certificates.
+func get(server *httptest.Server, ca *testCA, clientCerts ...tls.Certificate) (string, error) {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      pool,
+		ServerName:   "localhost",
+		Certificates: clientCerts,
+	}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth))
+	manager, err := certs.NewManager(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+
+	t.Run("should not verify clients by default", func(t *testing.T) {
+		tlsConfig, err := certs.NewServerTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: certs.ClientAuthNone}, manager)
+		require.NoError(t, err)
+		assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+		assert.Nil(t, tlsConfig.ClientCAs)
+		assert.NotNil(t, tlsConfig.GetCertificate)
+	})
+
+	t.Run("should fail when the client CA bundle is empty", func(t *testing.T) {
+		caFile := filepath.Join(dir, "empty.crt")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+		_, err := certs.NewServerTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: certs.ClientAuthRequire, ClientCAFile: caFile}, manager)
+		assert.ErrorContains(t, err, "no certificates found")
+	})
+
+	t.Run("should reject unknown verification modes", func(t *testing.T) {
+		_, err := certs.NewServerTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "sometimes"}, manager)
+		assert.ErrorContains(t, err, "sometimes")
+	})
+}
+
+func TestClientIdentityHandler(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	clientCert := ca.issue(t, "client.example.com", x509.ExtKeyUsageClientAuth)
+
+	t.Run("should expose the verified client identity", func(t *testing.T) {
+		server := newTestServer(t, ca, certs.ClientAuthRequire)
+
+		body, err := get(server, ca, clientCert)
+		require.NoError(t, err)
+		assert.Equal(t, "client.example.com|CN=client.example.com,O=Basic|client.example.com|127.0.0.1", body)
+	})
+
+	t.Run("should reject clients without certificate when required", func(t *testing.T) {
+		server := newTestServer(t, ca, certs.ClientAuthRequire)
+
+		_, err := get(server, ca)
+		assert.Error(t, err)
+	})
+
+	t.Run("should accept anonymous clients when requested", func(t *testing.T) {
+		server := newTestServer(t, ca, certs.ClientAuthRequest)
+
+		body, err := get(server, ca)
+		require.NoError(t, err)
+		assert.Equal(t, "anonymous", body)
+
+		body, err = get(server, ca, clientCert)
+		require.NoError(t, err)
+		assert.Contains(t, body, "client.example.com")
+	})
+
+	t.Run("should reject certificates from unknown authorities", func(t *testing.T) {
+		server := newTestServer(t, ca, certs.ClientAuthRequest)
+
+		_, err := get(server, ca, newTestCA(t).issue(t, "intruder", x509.ExtKeyUsageClientAuth))
+		assert.Error(t, err)
+	})
+
+	t.Run("should not expose an identity without mutual TLS", func(t *testing.T) {
+		server := newTestServer(t, ca, certs.ClientAuthNone)
+
+		body, err := get(server, ca, clientCert)
+		require.NoError(t, err)
+		assert.Equal(t, "anonymous", body)
+	})
+}
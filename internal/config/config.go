@@ -0,0 +1,757 @@
+// Package config provides the typed service configuration and its layered loading.
+// Values are resolved in order of increasing precedence: built-in defaults, a YAML
+// configuration file, BASIC_* environment variables and command line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the effective configuration of the service.
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	TLS         TLSConfig         `yaml:"tls"`
+	HTTP2       HTTP2Config       `yaml:"http2"`
+	HTTP3       HTTP3Config       `yaml:"http3"`
+	Compression CompressionConfig `yaml:"compression"`
+	Background  BackgroundConfig  `yaml:"background"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Limits      LimitsConfig      `yaml:"limits"`
+	Errors      ErrorsConfig      `yaml:"errors"`
+}
+
+// Listener modes accepted in ServerConfig.Mode.
+const (
+	ModeTLS = "tls" // HTTP/2 over TLS
+	ModeH2C = "h2c" // Cleartext HTTP/2 with prior knowledge or Upgrade, e.g. behind a service mesh sidecar
+)
+
+// ServerConfig controls where the service listens and how it shuts down.
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`          // Bind address of the HTTP/2 server, shared with HTTP/3 by default; empty disables it
+	Mode         string        `yaml:"mode"`          // Listener mode of the HTTP/2 server: tls or h2c
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // Maximum time active streams may take to finish on shutdown
+
+	UnixSocket       string `yaml:"unix_socket"`       // Path of a Unix domain socket served like addr, e.g. for sidecars
+	UnixSocketMode   string `yaml:"unix_socket_mode"`  // Octal file permissions of the Unix domain socket
+	SocketActivation bool   `yaml:"socket_activation"` // Also serve sockets passed by systemd via LISTEN_FDS
+}
+
+// UnixSocketPermissions returns the parsed UnixSocketMode. It must only be called
+// on a validated configuration.
+func (s ServerConfig) UnixSocketPermissions() fs.FileMode {
+	mode, _ := strconv.ParseUint(s.UnixSocketMode, 8, 32)
+	return fs.FileMode(mode)
+}
+
+// TLSConfig holds the server certificate used by both listeners and the
+// optional verification of client certificates (mutual TLS).
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`      // PEM encoded certificate chain
+	KeyFile      string `yaml:"key_file"`       // PEM encoded private key
+	ClientAuth   string `yaml:"client_auth"`    // Client certificate verification: none, request or require
+	ClientCAFile string `yaml:"client_ca_file"` // PEM bundle of CAs trusted to sign client certificates
+
+	SNIDir         string        `yaml:"sni_dir"`         // Directory of <name>.crt/<name>.key pairs selected by server name
+	ReloadInterval time.Duration `yaml:"reload_interval"` // How often certificate files are checked for changes, 0 disables reloading
+
+	Dev       bool   `yaml:"dev"`         // Serve an in-memory certificate from an ephemeral CA instead of CertFile/KeyFile
+	DevCAFile string `yaml:"dev_ca_file"` // Where to write the ephemeral CA in PEM format so clients can trust it
+}
+
+// HTTP2Config holds the timeouts and limits of the HTTP/2 server.
+type HTTP2Config struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+}
+
+// HTTP3Config controls the HTTP/3 server, which always uses TLS, and how it is
+// advertised to HTTP/2 clients.
+type HTTP3Config struct {
+	Enabled bool   `yaml:"enabled"` // Whether to serve HTTP/3 over QUIC
+	Addr    string `yaml:"addr"`    // UDP bind address, defaults to server.addr; required in h2c mode
+
+	AltSvc       bool          `yaml:"alt_svc"`         // Advertise HTTP/3 via Alt-Svc headers on HTTP/2 over TLS responses
+	AltSvcMaxAge time.Duration `yaml:"alt_svc_max_age"` // How long clients may cache the advertisement
+	Port         int           `yaml:"port"`            // Port to advertise if it differs from the bound port, e.g. behind NAT
+}
+
+// HTTP3Addr returns the address the HTTP/3 server binds to.
+func (c *Config) HTTP3Addr() string {
+	if c.HTTP3.Addr != "" {
+		return c.HTTP3.Addr
+	}
+	return c.Server.Addr
+}
+
+// UsesTLS reports whether any listener needs the server certificate.
+func (c *Config) UsesTLS() bool {
+	return c.Server.Mode == ModeTLS || c.HTTP3.Enabled
+}
+
+// CompressionConfig controls response compression of the Connect handlers.
+type CompressionConfig struct {
+	MinBytes int `yaml:"min_bytes"` // Messages smaller than this are sent uncompressed
+}
+
+// BackgroundConfig controls the Background RPC.
+type BackgroundConfig struct {
+	ProgressInterval    time.Duration   `yaml:"progress_interval"`      // Heartbeat repeating unchanged progress, unless requested otherwise
+	SaturationThreshold int             `yaml:"saturation_threshold"`   // Running jobs at which health reports NOT_SERVING, 0 disables
+	DependencyErrorRate float64         `yaml:"dependency_error_rate"`  // Share of failed calls to a downstream service at which health reports NOT_SERVING, 0 disables
+	DependencyWindow    int             `yaml:"dependency_window"`      // Latest calls per downstream service the error rate is computed over
+	MaxProcesses        int             `yaml:"max_processes"`          // Upper bound of the processes a single request may ask for
+	MaxRunningJobs      int             `yaml:"max_running_jobs"`       // Running jobs of all callers at which new ones are rejected, 0 for no limit
+	MaxRunningPerCaller int             `yaml:"max_running_per_caller"` // Running jobs of a single caller at which its new ones are rejected, 0 for no limit
+	Services            []ServiceConfig `yaml:"services"`               // Registry of downstream services the processes call
+	Retention           time.Duration   `yaml:"retention"`              // Time finished jobs are kept after completion, 0 keeps them
+	MaxFinishedJobs     int             `yaml:"max_finished_jobs"`      // Finished jobs kept, the earliest finished are evicted first, 0 for no limit
+	JanitorInterval     time.Duration   `yaml:"janitor_interval"`       // Interval of evicting finished jobs older than Retention
+}
+
+// ServiceConfig is a downstream service in the registry of BackgroundConfig.
+type ServiceConfig struct {
+	Name string `yaml:"name"` // Name reported in responses and traces
+	Type string `yaml:"type"` // Kind of API the service is called through, e.g. rest, rpc or grpc
+}
+
+// Targets returns the services called by a Background request for processes
+// downstream calls, cycling through the registry in order. Zero processes call
+// every registered service once.
+func (c BackgroundConfig) Targets(processes int) []ServiceConfig {
+	if processes == 0 {
+		processes = len(c.Services)
+	}
+	targets := make([]ServiceConfig, processes)
+	for i := range targets {
+		targets[i] = c.Services[i%len(c.Services)]
+	}
+	return targets
+}
+
+// AdminConfig controls the admin listener serving profiling and runtime
+// information. It is separate from the service listeners so it can be kept
+// private, which is why it is bound to localhost by default.
+type AdminConfig struct {
+	Addr string `yaml:"addr"` // Bind address of the cleartext admin server, empty disables it
+}
+
+// Log output formats accepted in LogConfig.Format.
+const (
+	LogFormatText = "text" // key=value pairs, for humans
+	LogFormatJSON = "json" // One JSON object per line, for log collectors
+)
+
+// LogConfig controls the structured log output.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // Minimum level: debug, info, warn or error
+	Format string `yaml:"format"` // Output format: text or json
+}
+
+// SlogLevel returns the parsed Level. It must only be called on a validated
+// configuration.
+func (l LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(l.Level))
+	return level
+}
+
+// TracingConfig controls the OpenTelemetry traces of the service.
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP collector URL such as http://localhost:4318, empty disables exporting
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces to record, calls with a sampled parent are always recorded
+}
+
+// Authentication modes accepted in AuthConfig.Mode.
+const (
+	AuthModeNone   = "none"    // Every caller is accepted without credentials
+	AuthModeAPIKey = "api_key" // Static API keys sent as bearer token or X-Api-Key header
+	AuthModeJWT    = "jwt"     // Bearer JWTs signed by a key of a local JWKS file
+)
+
+// AuthConfig controls how callers of the service authenticate.
+type AuthConfig struct {
+	Mode        string   `yaml:"mode"`          // Authentication mode: none, api_key or jwt
+	APIKeysFile string   `yaml:"api_keys_file"` // YAML map of principal to API key, required in api_key mode
+	JWKSFile    string   `yaml:"jwks_file"`     // JSON Web Key Set verifying JWT signatures, required in jwt mode
+	Issuer      string   `yaml:"issuer"`        // Required iss claim of JWTs, empty accepts any issuer
+	Audience    string   `yaml:"audience"`      // Required aud claim of JWTs, empty accepts any audience
+	Exempt      []string `yaml:"exempt"`        // Procedures callable without credentials, entries ending in / match a whole service
+
+	PolicyFile   string `yaml:"policy_file"`    // YAML authorization policy restricting procedures to principals, roles or scopes
+	PolicyDryRun bool   `yaml:"policy_dry_run"` // Only log calls denied by the policy instead of rejecting them
+}
+
+// Client keys accepted in LimitsConfig.Key, identifying whose calls share a limit.
+const (
+	LimitKeyPrincipal = "principal" // Authenticated principal, the peer IP for calls without one
+	LimitKeyPeer      = "peer"      // IP address of the peer
+	LimitKeyAPIKey    = "api_key"   // Credentials sent as bearer token or X-Api-Key header, the peer IP for calls without any
+)
+
+// Limit bounds the calls of a single client to a procedure. The zero Limit is
+// unlimited.
+type Limit struct {
+	Rate       float64 `yaml:"rate"`        // Calls per second refilling the token bucket, 0 disables rate limiting
+	Burst      int     `yaml:"burst"`       // Calls accepted at once before the rate applies, the size of the token bucket
+	MaxStreams int     `yaml:"max_streams"` // Streaming calls open at the same time, 0 is unlimited
+}
+
+// LimitsConfig controls the rate and concurrency limits of clients.
+type LimitsConfig struct {
+	Key        string           `yaml:"key"` // Client key limits are tracked by: principal, peer or api_key
+	Limit      `yaml:",inline"` // Limit of every procedure without an entry in Procedures
+	Procedures map[string]Limit `yaml:"procedures"` // Limits by procedure or by service ending in /, replacing the default limit
+}
+
+// For returns the limit of procedure, from the most specific entry in
+// Procedures or the default limit.
+func (l LimitsConfig) For(procedure string) Limit {
+	if limit, ok := l.Procedures[procedure]; ok {
+		return limit
+	}
+	limit, match := l.Limit, ""
+	for pattern, candidate := range l.Procedures {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(procedure, pattern) && len(pattern) > len(match) {
+			limit, match = candidate, pattern
+		}
+	}
+	return limit
+}
+
+// ErrorsConfig controls the details attached to errors returned to clients.
+type ErrorsConfig struct {
+	DebugInfo bool `yaml:"debug_info"` // Attach a DebugInfo with the cause and stack, reveals internals so only for development
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:         "127.0.0.1:8443",
+			Mode:         ModeTLS,
+			DrainTimeout: 30 * time.Second,
+
+			UnixSocketMode: "0660",
+		},
+		TLS: TLSConfig{
+			CertFile:       "./certs/local.crt",
+			KeyFile:        "./certs/local.key",
+			ClientAuth:     "none",
+			ReloadInterval: 30 * time.Second,
+		},
+		HTTP2: HTTP2Config{
+			ReadHeaderTimeout: time.Second,
+			ReadTimeout:       5 * time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			MaxHeaderBytes:    8 * 1024,
+		},
+		HTTP3: HTTP3Config{
+			Enabled:      true,
+			AltSvc:       true,
+			AltSvcMaxAge: 24 * time.Hour,
+		},
+		Compression: CompressionConfig{
+			MinBytes: 1024,
+		},
+		Background: BackgroundConfig{
+			ProgressInterval:    2 * time.Second,
+			SaturationThreshold: 100,
+			DependencyErrorRate: 0.5,
+			DependencyWindow:    20,
+			MaxProcesses:        20,
+			MaxRunningJobs:      200,
+			MaxRunningPerCaller: 20,
+			Retention:           time.Hour,
+			MaxFinishedJobs:     1000,
+			JanitorInterval:     time.Minute,
+			Services: []ServiceConfig{
+				{Name: "service-1", Type: "rest"},
+				{Name: "service-2", Type: "rpc"},
+				{Name: "service-3", Type: "grpc"},
+				{Name: "service-4", Type: "rest"},
+				{Name: "service-5", Type: "grpc"},
+			},
+		},
+		Admin: AdminConfig{
+			Addr: "127.0.0.1:9090",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatText,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			Mode: AuthModeNone,
+			Exempt: []string{
+				"/grpc.health.v1.Health/",
+				"/grpc.reflection.v1.ServerReflection/",
+				"/grpc.reflection.v1alpha.ServerReflection/",
+			},
+		},
+		Limits: LimitsConfig{
+			Key: LimitKeyPrincipal,
+			Procedures: map[string]Limit{
+				"/grpc.health.v1.Health/": {}, // Never limit probes
+			},
+		},
+	}
+}
+
+// setting describes a single configuration value together with the environment
+// variable and command line flag that override it.
+type setting struct {
+	key   string // Dotted path of the value in the configuration file
+	env   string
+	flag  string
+	usage string
+	field func(*Config) any // Returns a pointer to the field backing the setting
+}
+
+// settings lists every value that can be overridden by environment or flags.
+var settings = []setting{
+	{"server.addr", "BASIC_SERVER_ADDR", "server-addr", "server address to bind to", func(c *Config) any { return &c.Server.Addr }},
+	{"server.mode", "BASIC_SERVER_MODE", "server-mode", "listener mode of the HTTP/2 server: tls or h2c (cleartext)", func(c *Config) any { return &c.Server.Mode }},
+	{"server.drain_timeout", "BASIC_SERVER_DRAIN_TIMEOUT", "drain-timeout", "maximum time to wait for active streams to finish on shutdown", func(c *Config) any { return &c.Server.DrainTimeout }},
+	{"server.unix_socket", "BASIC_SERVER_UNIX_SOCKET", "server-unix-socket", "path of a Unix domain socket to serve on in addition to the server address", func(c *Config) any { return &c.Server.UnixSocket }},
+	{"server.unix_socket_mode", "BASIC_SERVER_UNIX_SOCKET_MODE", "server-unix-socket-mode", "octal file permissions of the Unix domain socket", func(c *Config) any { return &c.Server.UnixSocketMode }},
+	{"server.socket_activation", "BASIC_SERVER_SOCKET_ACTIVATION", "socket-activation", "serve sockets passed by systemd socket activation (LISTEN_FDS)", func(c *Config) any { return &c.Server.SocketActivation }},
+	{"tls.cert_file", "BASIC_TLS_CERT_FILE", "tls-cert-file", "path to the PEM encoded server certificate", func(c *Config) any { return &c.TLS.CertFile }},
+	{"tls.key_file", "BASIC_TLS_KEY_FILE", "tls-key-file", "path to the PEM encoded server private key", func(c *Config) any { return &c.TLS.KeyFile }},
+	{"tls.client_auth", "BASIC_TLS_CLIENT_AUTH", "tls-client-auth", "client certificate verification mode: none, request or require", func(c *Config) any { return &c.TLS.ClientAuth }},
+	{"tls.client_ca_file", "BASIC_TLS_CLIENT_CA_FILE", "tls-client-ca-file", "path to the PEM bundle of CAs trusted for client certificates", func(c *Config) any { return &c.TLS.ClientCAFile }},
+	{"tls.sni_dir", "BASIC_TLS_SNI_DIR", "tls-sni-dir", "directory of <name>.crt/<name>.key certificate pairs selected by SNI", func(c *Config) any { return &c.TLS.SNIDir }},
+	{"tls.reload_interval", "BASIC_TLS_RELOAD_INTERVAL", "tls-reload-interval", "interval for checking certificate files for changes, 0 disables reloading", func(c *Config) any { return &c.TLS.ReloadInterval }},
+	{"tls.dev", "BASIC_TLS_DEV", "dev-tls", "generate an ephemeral development CA and server certificate instead of loading them", func(c *Config) any { return &c.TLS.Dev }},
+	{"tls.dev_ca_file", "BASIC_TLS_DEV_CA_FILE", "dev-tls-ca-file", "write the generated development CA in PEM format to this path", func(c *Config) any { return &c.TLS.DevCAFile }},
+	{"http2.read_header_timeout", "BASIC_HTTP2_READ_HEADER_TIMEOUT", "http2-read-header-timeout", "time allowed to read request headers", func(c *Config) any { return &c.HTTP2.ReadHeaderTimeout }},
+	{"http2.read_timeout", "BASIC_HTTP2_READ_TIMEOUT", "http2-read-timeout", "maximum duration for reading an entire request", func(c *Config) any { return &c.HTTP2.ReadTimeout }},
+	{"http2.write_timeout", "BASIC_HTTP2_WRITE_TIMEOUT", "http2-write-timeout", "maximum duration before timing out writes of a response", func(c *Config) any { return &c.HTTP2.WriteTimeout }},
+	{"http2.max_header_bytes", "BASIC_HTTP2_MAX_HEADER_BYTES", "http2-max-header-bytes", "maximum size of request headers in bytes", func(c *Config) any { return &c.HTTP2.MaxHeaderBytes }},
+	{"http3.enabled", "BASIC_HTTP3_ENABLED", "http3-enabled", "serve HTTP/3 over QUIC", func(c *Config) any { return &c.HTTP3.Enabled }},
+	{"http3.addr", "BASIC_HTTP3_ADDR", "http3-addr", "UDP address of the HTTP/3 server, defaults to the server address", func(c *Config) any { return &c.HTTP3.Addr }},
+	{"http3.alt_svc", "BASIC_HTTP3_ALT_SVC", "http3-alt-svc", "advertise HTTP/3 via Alt-Svc headers on HTTP/2 responses", func(c *Config) any { return &c.HTTP3.AltSvc }},
+	{"http3.alt_svc_max_age", "BASIC_HTTP3_ALT_SVC_MAX_AGE", "http3-alt-svc-max-age", "how long clients may cache the HTTP/3 advertisement", func(c *Config) any { return &c.HTTP3.AltSvcMaxAge }},
+	{"http3.port", "BASIC_HTTP3_PORT", "http3-port", "UDP port to advertise for HTTP/3 if it differs from the bound port", func(c *Config) any { return &c.HTTP3.Port }},
+	{"compression.min_bytes", "BASIC_COMPRESSION_MIN_BYTES", "compression-min-bytes", "minimum message size in bytes before responses are compressed", func(c *Config) any { return &c.Compression.MinBytes }},
+	{"background.progress_interval", "BASIC_BACKGROUND_PROGRESS_INTERVAL", "background-progress-interval", "interval of Background heartbeats repeating unchanged progress, unless the request sets one", func(c *Config) any { return &c.Background.ProgressInterval }},
+	{"background.saturation_threshold", "BASIC_BACKGROUND_SATURATION_THRESHOLD", "background-saturation-threshold", "number of running Background jobs at which the service reports NOT_SERVING, 0 disables", func(c *Config) any { return &c.Background.SaturationThreshold }},
+	{"background.dependency_error_rate", "BASIC_BACKGROUND_DEPENDENCY_ERROR_RATE", "background-dependency-error-rate", "share of failed calls to a downstream service between 0 and 1 at which the service reports NOT_SERVING, 0 disables", func(c *Config) any { return &c.Background.DependencyErrorRate }},
+	{"background.dependency_window", "BASIC_BACKGROUND_DEPENDENCY_WINDOW", "background-dependency-window", "number of latest calls per downstream service the error rate is computed over", func(c *Config) any { return &c.Background.DependencyWindow }},
+	{"background.max_processes", "BASIC_BACKGROUND_MAX_PROCESSES", "background-max-processes", "maximum number of downstream calls a single Background request may ask for", func(c *Config) any { return &c.Background.MaxProcesses }},
+	{"background.max_running_jobs", "BASIC_BACKGROUND_MAX_RUNNING_JOBS", "background-max-running-jobs", "running Background jobs of all callers at which new ones are rejected, 0 for no limit", func(c *Config) any { return &c.Background.MaxRunningJobs }},
+	{"background.max_running_per_caller", "BASIC_BACKGROUND_MAX_RUNNING_PER_CALLER", "background-max-running-per-caller", "running Background jobs of a single caller at which its new ones are rejected, 0 for no limit", func(c *Config) any { return &c.Background.MaxRunningPerCaller }},
+	{"background.retention", "BASIC_BACKGROUND_RETENTION", "background-retention", "time finished Background jobs are kept after completion, 0 keeps them", func(c *Config) any { return &c.Background.Retention }},
+	{"background.max_finished_jobs", "BASIC_BACKGROUND_MAX_FINISHED_JOBS", "background-max-finished-jobs", "finished Background jobs kept, the earliest finished are evicted first, 0 for no limit", func(c *Config) any { return &c.Background.MaxFinishedJobs }},
+	{"background.janitor_interval", "BASIC_BACKGROUND_JANITOR_INTERVAL", "background-janitor-interval", "interval of evicting finished Background jobs older than the retention", func(c *Config) any { return &c.Background.JanitorInterval }},
+	{"admin.addr", "BASIC_ADMIN_ADDR", "admin-addr", "address of the admin server exposing Prometheus metrics, pprof, expvar and runtime info, empty disables it", func(c *Config) any { return &c.Admin.Addr }},
+	{"log.level", "BASIC_LOG_LEVEL", "log-level", "minimum log level: debug, info, warn or error", func(c *Config) any { return &c.Log.Level }},
+	{"log.format", "BASIC_LOG_FORMAT", "log-format", "log output format: text or json", func(c *Config) any { return &c.Log.Format }},
+	{"tracing.endpoint", "BASIC_TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector URL to export traces to, empty disables exporting", func(c *Config) any { return &c.Tracing.Endpoint }},
+	{"tracing.sample_ratio", "BASIC_TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces to record between 0 and 1", func(c *Config) any { return &c.Tracing.SampleRatio }},
+	{"auth.mode", "BASIC_AUTH_MODE", "auth-mode", "authentication mode: none, api_key or jwt", func(c *Config) any { return &c.Auth.Mode }},
+	{"auth.api_keys_file", "BASIC_AUTH_API_KEYS_FILE", "auth-api-keys-file", "path to a YAML map of principal to API key for api_key mode", func(c *Config) any { return &c.Auth.APIKeysFile }},
+	{"auth.jwks_file", "BASIC_AUTH_JWKS_FILE", "auth-jwks-file", "path to the JSON Web Key Set verifying JWTs in jwt mode", func(c *Config) any { return &c.Auth.JWKSFile }},
+	{"auth.issuer", "BASIC_AUTH_ISSUER", "auth-issuer", "required issuer of JWTs, empty accepts any", func(c *Config) any { return &c.Auth.Issuer }},
+	{"auth.audience", "BASIC_AUTH_AUDIENCE", "auth-audience", "required audience of JWTs, empty accepts any", func(c *Config) any { return &c.Auth.Audience }},
+	{"auth.exempt", "BASIC_AUTH_EXEMPT", "auth-exempt", "comma separated procedures callable without credentials, entries ending in / match a whole service", func(c *Config) any { return &c.Auth.Exempt }},
+	{"auth.policy_file", "BASIC_AUTH_POLICY_FILE", "auth-policy-file", "path to a YAML authorization policy restricting procedures to principals, roles or scopes", func(c *Config) any { return &c.Auth.PolicyFile }},
+	{"auth.policy_dry_run", "BASIC_AUTH_POLICY_DRY_RUN", "auth-policy-dry-run", "only log calls denied by the authorization policy instead of rejecting them", func(c *Config) any { return &c.Auth.PolicyDryRun }},
+	{"limits.key", "BASIC_LIMITS_KEY", "limits-key", "client key rate and stream limits are tracked by: principal, peer or api_key", func(c *Config) any { return &c.Limits.Key }},
+	{"limits.rate", "BASIC_LIMITS_RATE", "limits-rate", "calls per second and client to each procedure, 0 disables rate limiting", func(c *Config) any { return &c.Limits.Rate }},
+	{"limits.burst", "BASIC_LIMITS_BURST", "limits-burst", "calls a client may make at once before limits.rate applies", func(c *Config) any { return &c.Limits.Burst }},
+	{"limits.max_streams", "BASIC_LIMITS_MAX_STREAMS", "limits-max-streams", "streaming calls a client may have open per procedure, 0 is unlimited", func(c *Config) any { return &c.Limits.MaxStreams }},
+	{"errors.debug_info", "BASIC_ERRORS_DEBUG_INFO", "errors-debug-info", "attach the cause and stack of errors as DebugInfo detail, only for development", func(c *Config) any { return &c.Errors.DebugInfo }},
+}
+
+// Load resolves the configuration from defaults, the configuration file, the
+// environment and the command line. Flags for every setting plus -config are
+// registered on fs before args are parsed, so callers may add their own flags
+// to fs beforehand. lookupEnv is typically os.LookupEnv.
+//
+// The configuration file is taken from -config or BASIC_CONFIG. The returned
+// configuration has been validated.
+func Load(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	configFile := fs.String("config", "", "path to a YAML configuration file (env BASIC_CONFIG)")
+	overrides := map[string]string{}
+	for _, s := range settings {
+		fs.Var(&flagValue{def: cfg, setting: s, overrides: overrides}, s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("BASIC_CONFIG")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok {
+			if err := setValue(s.field(cfg), value); err != nil {
+				return nil, fmt.Errorf("%s: invalid value %q: %w", s.env, value, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := overrides[s.flag]; ok {
+			if err := setValue(s.field(cfg), value); err != nil {
+				return nil, fmt.Errorf("-%s: invalid value %q: %w", s.flag, value, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile merges the YAML file at path into the configuration. Unknown keys
+// are rejected so typos surface at startup.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration for values the service cannot run with.
+// All problems are reported together, each prefixed with the setting's key.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Addr == "" {
+		if c.Server.UnixSocket == "" && !c.Server.SocketActivation {
+			invalid("server.addr", "must be set unless server.unix_socket or server.socket_activation is configured")
+		}
+	} else if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		invalid("server.addr", "must be in the form host:port: %v", err)
+	}
+	if mode, err := strconv.ParseUint(c.Server.UnixSocketMode, 8, 32); err != nil || mode > 0o777 {
+		invalid("server.unix_socket_mode", "must be octal file permissions such as 0660, got %q", c.Server.UnixSocketMode)
+	}
+	switch c.Server.Mode {
+	case ModeTLS:
+	case ModeH2C:
+		if c.HTTP3.Enabled && c.HTTP3.Addr == "" {
+			invalid("http3.addr", "must be set when server.mode is %q, or disable HTTP/3 with http3.enabled=false", ModeH2C)
+		}
+	default:
+		invalid("server.mode", "must be one of %s or %s, got %q", ModeTLS, ModeH2C, c.Server.Mode)
+	}
+	if c.Server.Mode == ModeTLS && c.HTTP3.Enabled && c.HTTP3Addr() == "" && !c.Server.SocketActivation {
+		invalid("http3.addr", "must be set when server.addr is empty, or disable HTTP/3 with http3.enabled=false")
+	}
+	if c.HTTP3.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP3.Addr); err != nil {
+			invalid("http3.addr", "must be in the form host:port: %v", err)
+		}
+	}
+	if c.HTTP3.AltSvcMaxAge < time.Second && c.HTTP3.AltSvc {
+		invalid("http3.alt_svc_max_age", "must be at least 1s, got %s", c.HTTP3.AltSvcMaxAge)
+	}
+	if c.HTTP3.Port < 0 || c.HTTP3.Port > 65535 {
+		invalid("http3.port", "must be a valid port number, got %d", c.HTTP3.Port)
+	}
+	if c.Server.DrainTimeout <= 0 {
+		invalid("server.drain_timeout", "must be positive, got %s", c.Server.DrainTimeout)
+	}
+	if c.TLS.CertFile == "" && !c.TLS.Dev && c.UsesTLS() {
+		invalid("tls.cert_file", "must not be empty")
+	}
+	if c.TLS.KeyFile == "" && !c.TLS.Dev && c.UsesTLS() {
+		invalid("tls.key_file", "must not be empty")
+	}
+	if c.TLS.DevCAFile != "" && !c.TLS.Dev {
+		invalid("tls.dev_ca_file", "requires tls.dev to be enabled")
+	}
+	switch c.TLS.ClientAuth {
+	case "none":
+	case "request", "require":
+		if c.TLS.ClientCAFile == "" {
+			invalid("tls.client_ca_file", "must be set when tls.client_auth is %q", c.TLS.ClientAuth)
+		}
+	default:
+		invalid("tls.client_auth", "must be one of none, request or require, got %q", c.TLS.ClientAuth)
+	}
+	if c.TLS.ReloadInterval < 0 {
+		invalid("tls.reload_interval", "must not be negative, got %s", c.TLS.ReloadInterval)
+	}
+	if c.HTTP2.ReadHeaderTimeout <= 0 {
+		invalid("http2.read_header_timeout", "must be positive, got %s", c.HTTP2.ReadHeaderTimeout)
+	}
+	if c.HTTP2.ReadTimeout < 0 {
+		invalid("http2.read_timeout", "must not be negative, got %s", c.HTTP2.ReadTimeout)
+	}
+	if c.HTTP2.WriteTimeout < 0 {
+		invalid("http2.write_timeout", "must not be negative, got %s", c.HTTP2.WriteTimeout)
+	}
+	if c.HTTP2.MaxHeaderBytes <= 0 {
+		invalid("http2.max_header_bytes", "must be positive, got %d", c.HTTP2.MaxHeaderBytes)
+	}
+	if c.Compression.MinBytes < 0 {
+		invalid("compression.min_bytes", "must not be negative, got %d", c.Compression.MinBytes)
+	}
+	if c.Background.ProgressInterval <= 0 {
+		invalid("background.progress_interval", "must be positive, got %s", c.Background.ProgressInterval)
+	}
+	if c.Background.SaturationThreshold < 0 {
+		invalid("background.saturation_threshold", "must not be negative, got %d", c.Background.SaturationThreshold)
+	}
+	if c.Background.DependencyErrorRate < 0 || c.Background.DependencyErrorRate > 1 {
+		invalid("background.dependency_error_rate", "must be between 0 and 1, got %g", c.Background.DependencyErrorRate)
+	}
+	if c.Background.DependencyWindow < 1 {
+		invalid("background.dependency_window", "must be positive, got %d", c.Background.DependencyWindow)
+	}
+	if c.Background.MaxProcesses < 1 || c.Background.MaxProcesses > 100 {
+		invalid("background.max_processes", "must be between 1 and 100, got %d", c.Background.MaxProcesses)
+	}
+	if c.Background.MaxRunningJobs < 0 {
+		invalid("background.max_running_jobs", "must not be negative, got %d", c.Background.MaxRunningJobs)
+	}
+	if c.Background.MaxRunningPerCaller < 0 {
+		invalid("background.max_running_per_caller", "must not be negative, got %d", c.Background.MaxRunningPerCaller)
+	}
+	if c.Background.Retention < 0 {
+		invalid("background.retention", "must not be negative, got %s", c.Background.Retention)
+	}
+	if c.Background.MaxFinishedJobs < 0 {
+		invalid("background.max_finished_jobs", "must not be negative, got %d", c.Background.MaxFinishedJobs)
+	}
+	if c.Background.JanitorInterval <= 0 {
+		invalid("background.janitor_interval", "must be positive, got %s", c.Background.JanitorInterval)
+	}
+	if len(c.Background.Services) == 0 {
+		invalid("background.services", "must list at least one service")
+	}
+	names := map[string]bool{}
+	for i, service := range c.Background.Services {
+		switch {
+		case service.Name == "":
+			invalid(fmt.Sprintf("background.services[%d].name", i), "must not be empty")
+		case names[service.Name]:
+			invalid(fmt.Sprintf("background.services[%d].name", i), "must be unique, %q is registered twice", service.Name)
+		}
+		names[service.Name] = true
+		if service.Type == "" {
+			invalid(fmt.Sprintf("background.services[%d].type", i), "must not be empty")
+		}
+	}
+	if c.Admin.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Admin.Addr); err != nil {
+			invalid("admin.addr", "must be in the form host:port: %v", err)
+		}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		invalid("log.level", "must be one of debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		invalid("log.format", "must be one of %s or %s, got %q", LogFormatText, LogFormatJSON, c.Log.Format)
+	}
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("tracing.endpoint", "must be an http or https URL such as http://localhost:4318, got %q", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	switch c.Auth.Mode {
+	case AuthModeNone:
+	case AuthModeAPIKey:
+		if c.Auth.APIKeysFile == "" {
+			invalid("auth.api_keys_file", "must be set when auth.mode is %q", AuthModeAPIKey)
+		}
+	case AuthModeJWT:
+		if c.Auth.JWKSFile == "" {
+			invalid("auth.jwks_file", "must be set when auth.mode is %q", AuthModeJWT)
+		}
+	default:
+		invalid("auth.mode", "must be one of %s, %s or %s, got %q", AuthModeNone, AuthModeAPIKey, AuthModeJWT, c.Auth.Mode)
+	}
+	for _, procedure := range c.Auth.Exempt {
+		if !strings.HasPrefix(procedure, "/") {
+			invalid("auth.exempt", "entries must start with /, got %q", procedure)
+		}
+	}
+	if c.Auth.PolicyFile != "" && c.Auth.Mode == AuthModeNone {
+		invalid("auth.policy_file", "requires auth.mode %s or %s to identify callers", AuthModeAPIKey, AuthModeJWT)
+	}
+	switch c.Limits.Key {
+	case LimitKeyPrincipal, LimitKeyPeer, LimitKeyAPIKey:
+	default:
+		invalid("limits.key", "must be one of %s, %s or %s, got %q", LimitKeyPrincipal, LimitKeyPeer, LimitKeyAPIKey, c.Limits.Key)
+	}
+	validateLimit := func(key string, limit Limit) {
+		if limit.Rate < 0 {
+			invalid(key+".rate", "must not be negative, got %g", limit.Rate)
+		}
+		if limit.Rate > 0 && limit.Burst < 1 {
+			invalid(key+".burst", "must be at least 1 when the rate is set, got %d", limit.Burst)
+		}
+		if limit.MaxStreams < 0 {
+			invalid(key+".max_streams", "must not be negative, got %d", limit.MaxStreams)
+		}
+	}
+	validateLimit("limits", c.Limits.Limit)
+	for procedure, limit := range c.Limits.Procedures {
+		if !strings.HasPrefix(procedure, "/") {
+			invalid("limits.procedures", "entries must start with /, got %q", procedure)
+		}
+		validateLimit("limits.procedures."+procedure, limit)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Write prints the configuration as YAML in the same format accepted by -config.
+func (c *Config) Write(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// flagValue is a flag.Value that records the raw flag value so it can be applied
+// after the configuration file and environment have been merged.
+type flagValue struct {
+	def       *Config
+	setting   setting
+	overrides map[string]string
+}
+
+// String returns the default value shown in the flag usage.
+func (v *flagValue) String() string {
+	if v.def == nil {
+		return ""
+	}
+	return fmt.Sprint(reflectValue(v.setting.field(v.def)))
+}
+
+// Set validates and records the value passed on the command line.
+func (v *flagValue) Set(value string) error {
+	var probe Config
+	if err := setValue(v.setting.field(&probe), value); err != nil {
+		return err
+	}
+	v.overrides[v.setting.flag] = value
+	return nil
+}
+
+// IsBoolFlag allows boolean settings to be passed without a value.
+func (v *flagValue) IsBoolFlag() bool {
+	_, ok := v.setting.field(&Config{}).(*bool)
+	return ok
+}
+
+// reflectValue dereferences a pointer returned by setting.field.
+func reflectValue(field any) any {
+	switch f := field.(type) {
+	case *string:
+		return *f
+	case *int:
+		return *f
+	case *float64:
+		return *f
+	case *[]string:
+		return strings.Join(*f, ",")
+	case *bool:
+		return *f
+	case *time.Duration:
+		return *f
+	default:
+		return nil
+	}
+}
+
+// setValue parses value according to the type of field and stores it.
+func setValue(field any, value string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*f = n
+	case *float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*f = n
+	case *[]string:
+		*f = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*f = append(*f, item)
+			}
+		}
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*f = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*f = d
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
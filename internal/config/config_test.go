@@ -0,0 +1,495 @@
+package config_test
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a lookup function backed by the given map.
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// writeFile writes a configuration file into a temporary directory.
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should return defaults without overrides", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, config.Default(), cfg)
+	})
+
+	t.Run("should merge values from the config file", func(t *testing.T) {
+		path := writeFile(t, "server:\n  addr: 0.0.0.0:9443\nbackground:\n  progress_interval: 500ms\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path}, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, "0.0.0.0:9443", cfg.Server.Addr)
+		assert.Equal(t, 500*time.Millisecond, cfg.Background.ProgressInterval)
+		assert.Equal(t, config.Default().TLS, cfg.TLS)
+	})
+
+	t.Run("should read the config file from BASIC_CONFIG", func(t *testing.T) {
+		path := writeFile(t, "compression:\n  min_bytes: 2048\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{"BASIC_CONFIG": path}))
+		require.NoError(t, err)
+		assert.Equal(t, 2048, cfg.Compression.MinBytes)
+	})
+
+	t.Run("should apply file, environment and flags in order of precedence", func(t *testing.T) {
+		path := writeFile(t, "server:\n  addr: 127.0.0.1:1000\n  drain_timeout: 10s\nhttp2:\n  max_header_bytes: 100\n")
+		environment := env(map[string]string{
+			"BASIC_SERVER_ADDR":            "127.0.0.1:2000",
+			"BASIC_HTTP2_MAX_HEADER_BYTES": "200",
+		})
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path, "-server-addr", "127.0.0.1:3000"}, environment)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:3000", cfg.Server.Addr)
+		assert.Equal(t, 10*time.Second, cfg.Server.DrainTimeout)
+		assert.Equal(t, 200, cfg.HTTP2.MaxHeaderBytes)
+	})
+
+	t.Run("should enable boolean settings with a bare flag", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-errors-debug-info"}, env(nil))
+		require.NoError(t, err)
+		assert.True(t, cfg.Errors.DebugInfo)
+		assert.False(t, config.Default().Errors.DebugInfo)
+	})
+
+	t.Run("should serve the admin server on localhost unless disabled", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{"BASIC_ADMIN_ADDR": ""}))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Admin.Addr)
+		assert.Equal(t, "127.0.0.1:9090", config.Default().Admin.Addr)
+	})
+
+	t.Run("should reject unknown keys in the config file", func(t *testing.T) {
+		path := writeFile(t, "server:\n  adress: 127.0.0.1:1000\n")
+
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path}, env(nil))
+		assert.ErrorContains(t, err, "adress")
+	})
+
+	t.Run("should reject malformed environment values", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{"BASIC_SERVER_DRAIN_TIMEOUT": "soon"}))
+		assert.ErrorContains(t, err, "BASIC_SERVER_DRAIN_TIMEOUT")
+	})
+
+	t.Run("should reject malformed flag values", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+
+		_, err := config.Load(fs, []string{"-compression-min-bytes", "many"}, env(nil))
+		assert.ErrorContains(t, err, "compression-min-bytes")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should accept the defaults", func(t *testing.T) {
+		assert.NoError(t, config.Default().Validate())
+	})
+
+	t.Run("should report every invalid setting", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Addr = "localhost"
+		cfg.TLS.CertFile = ""
+		cfg.Background.ProgressInterval = 0
+		cfg.Admin.Addr = "9090"
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "server.addr")
+		assert.ErrorContains(t, err, "tls.cert_file")
+		assert.ErrorContains(t, err, "background.progress_interval")
+		assert.ErrorContains(t, err, "admin.addr")
+	})
+}
+
+func TestValidateLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse log levels", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Log.Level = "debug"
+
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, slog.LevelDebug, cfg.Log.SlogLevel())
+	})
+
+	t.Run("should reject unknown levels and formats", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Log.Level = "verbose"
+		cfg.Log.Format = "logfmt"
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "log.level")
+		assert.ErrorContains(t, err, "log.format")
+	})
+}
+
+func TestValidateAuth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should exempt health and reflection by default", func(t *testing.T) {
+		cfg := config.Default()
+
+		assert.Equal(t, config.AuthModeNone, cfg.Auth.Mode)
+		assert.Contains(t, cfg.Auth.Exempt, "/grpc.health.v1.Health/")
+		assert.Contains(t, cfg.Auth.Exempt, "/grpc.reflection.v1.ServerReflection/")
+	})
+
+	t.Run("should require the credentials file of the mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Auth.Mode = config.AuthModeAPIKey
+		assert.ErrorContains(t, cfg.Validate(), "auth.api_keys_file")
+
+		cfg.Auth.Mode = config.AuthModeJWT
+		assert.ErrorContains(t, cfg.Validate(), "auth.jwks_file")
+	})
+
+	t.Run("should reject unknown modes and relative procedures", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Auth.Mode = "basic"
+		cfg.Auth.Exempt = []string{"grpc.health.v1.Health/"}
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "auth.mode")
+		assert.ErrorContains(t, err, "auth.exempt")
+	})
+
+	t.Run("should require authentication for authorization policies", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Auth.PolicyFile = "policy.yaml"
+		assert.ErrorContains(t, cfg.Validate(), "auth.policy_file")
+
+		cfg.Auth.Mode = config.AuthModeJWT
+		cfg.Auth.JWKSFile = "jwks.json"
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should parse exempt procedures from the environment", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{
+			"BASIC_AUTH_EXEMPT": "/grpc.health.v1.Health/, /basic.v1.BasicService/Hello",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/grpc.health.v1.Health/", "/basic.v1.BasicService/Hello"}, cfg.Auth.Exempt)
+	})
+}
+
+func TestValidateBackground(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should call every registered service once without processes", func(t *testing.T) {
+		cfg := config.Default()
+
+		assert.Equal(t, cfg.Background.Services, cfg.Background.Targets(0))
+	})
+
+	t.Run("should cycle through the registry for more processes than services", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.Services = []config.ServiceConfig{{Name: "a", Type: "rest"}, {Name: "b", Type: "grpc"}}
+
+		targets := cfg.Background.Targets(3)
+		assert.Equal(t, []config.ServiceConfig{{Name: "a", Type: "rest"}, {Name: "b", Type: "grpc"}, {Name: "a", Type: "rest"}}, targets)
+	})
+
+	t.Run("should reject an empty registry and invalid bounds", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.MaxProcesses = 101
+		cfg.Background.Services = nil
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "background.max_processes: must be between 1 and 100")
+		assert.ErrorContains(t, err, "background.services: must list at least one service")
+	})
+
+	t.Run("should reject unnamed, duplicate and untyped services", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.Services = []config.ServiceConfig{{Name: "a", Type: "rest"}, {Name: "a", Type: "rpc"}, {Type: "grpc"}, {Name: "b"}}
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, `background.services[1].name: must be unique, "a" is registered twice`)
+		assert.ErrorContains(t, err, "background.services[2].name: must not be empty")
+		assert.ErrorContains(t, err, "background.services[3].type: must not be empty")
+	})
+
+	t.Run("should read the registry from the config file", func(t *testing.T) {
+		path := writeFile(t, "background:\n  max_processes: 8\n  services:\n    - name: inventory\n      type: grpc\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path}, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, 8, cfg.Background.MaxProcesses)
+		assert.Equal(t, []config.ServiceConfig{{Name: "inventory", Type: "grpc"}}, cfg.Background.Services)
+	})
+
+	t.Run("should reject negative limits of running jobs", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.MaxRunningJobs = -1
+		cfg.Background.MaxRunningPerCaller = -1
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "background.max_running_jobs: must not be negative, got -1")
+		assert.ErrorContains(t, err, "background.max_running_per_caller: must not be negative, got -1")
+	})
+
+	t.Run("should reject negative retention bounds and a non-positive janitor interval", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.Retention = -time.Second
+		cfg.Background.MaxFinishedJobs = -1
+		cfg.Background.JanitorInterval = 0
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "background.retention: must not be negative, got -1s")
+		assert.ErrorContains(t, err, "background.max_finished_jobs: must not be negative, got -1")
+		assert.ErrorContains(t, err, "background.janitor_interval: must be positive, got 0s")
+	})
+
+	t.Run("should read retention bounds from the environment", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{
+			"BASIC_BACKGROUND_RETENTION":         "10m",
+			"BASIC_BACKGROUND_MAX_FINISHED_JOBS": "0",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, cfg.Background.Retention)
+		assert.Equal(t, 0, cfg.Background.MaxFinishedJobs)
+		assert.Equal(t, time.Minute, cfg.Background.JanitorInterval)
+	})
+
+	t.Run("should reject dependency error rates outside 0 and 1 and an empty window", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Background.DependencyErrorRate = 1.5
+		cfg.Background.DependencyWindow = 0
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "background.dependency_error_rate: must be between 0 and 1, got 1.5")
+		assert.ErrorContains(t, err, "background.dependency_window: must be positive, got 0")
+	})
+}
+
+func TestValidateLimits(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should not limit anything by default", func(t *testing.T) {
+		cfg := config.Default()
+
+		assert.Equal(t, config.Limit{}, cfg.Limits.For("/basic.v1.BasicService/Background"))
+	})
+
+	t.Run("should pick the most specific procedure limit", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Limits.Rate, cfg.Limits.Burst = 10, 20
+		cfg.Limits.Procedures["/basic.v1.BasicService/"] = config.Limit{Rate: 5, Burst: 5}
+		cfg.Limits.Procedures["/basic.v1.BasicService/Background"] = config.Limit{MaxStreams: 2}
+
+		assert.Equal(t, config.Limit{MaxStreams: 2}, cfg.Limits.For("/basic.v1.BasicService/Background"))
+		assert.Equal(t, config.Limit{Rate: 5, Burst: 5}, cfg.Limits.For("/basic.v1.BasicService/Hello"))
+		assert.Equal(t, config.Limit{}, cfg.Limits.For("/grpc.health.v1.Health/Check"))
+		assert.Equal(t, config.Limit{Rate: 10, Burst: 20}, cfg.Limits.For("/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"))
+	})
+
+	t.Run("should reject invalid keys and limits", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Limits.Key = "ip"
+		cfg.Limits.Rate = 10
+		cfg.Limits.Procedures["basic.v1.BasicService/"] = config.Limit{MaxStreams: -1}
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "limits.key")
+		assert.ErrorContains(t, err, "limits.burst: must be at least 1")
+		assert.ErrorContains(t, err, "limits.procedures: entries must start with /")
+		assert.ErrorContains(t, err, "limits.procedures.basic.v1.BasicService/.max_streams")
+	})
+
+	t.Run("should read procedure limits from the config file", func(t *testing.T) {
+		path := writeFile(t, "limits:\n  rate: 10\n  burst: 20\n  procedures:\n    /basic.v1.BasicService/Background:\n      max_streams: 2\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path, "-limits-key", "peer"}, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, config.LimitKeyPeer, cfg.Limits.Key)
+		assert.Equal(t, config.Limit{Rate: 10, Burst: 20}, cfg.Limits.Limit)
+		assert.Equal(t, config.Limit{MaxStreams: 2}, cfg.Limits.For("/basic.v1.BasicService/Background"))
+		assert.Contains(t, cfg.Limits.Procedures, "/grpc.health.v1.Health/")
+	})
+}
+
+func TestValidateTracing(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should accept an OTLP/HTTP endpoint", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Tracing.Endpoint = "http://localhost:4318"
+		cfg.Tracing.SampleRatio = 0.25
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should reject invalid endpoints and ratios", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Tracing.Endpoint = "localhost:4318"
+		cfg.Tracing.SampleRatio = 1.5
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "tracing.endpoint")
+		assert.ErrorContains(t, err, "tracing.sample_ratio")
+	})
+
+	t.Run("should parse the sample ratio from flags", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-tracing-sample-ratio", "0.1"}, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, 0.1, cfg.Tracing.SampleRatio)
+	})
+}
+
+func TestValidateClientAuth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should require a client CA bundle for mutual TLS", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TLS.ClientAuth = "require"
+
+		assert.ErrorContains(t, cfg.Validate(), "tls.client_ca_file")
+
+		cfg.TLS.ClientCAFile = "./certs/clients.crt"
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should reject unknown verification modes", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TLS.ClientAuth = "optional"
+
+		assert.ErrorContains(t, cfg.Validate(), "tls.client_auth")
+	})
+}
+
+func TestValidateDevelopmentTLS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should not require certificate files in development mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TLS.CertFile, cfg.TLS.KeyFile = "", ""
+		cfg.TLS.Dev = true
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should reject a CA output path without development mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TLS.DevCAFile = "./certs/dev-ca.crt"
+
+		assert.ErrorContains(t, cfg.Validate(), "tls.dev_ca_file")
+	})
+}
+
+func TestValidateH2C(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should require a separate HTTP/3 address in h2c mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Mode = config.ModeH2C
+
+		assert.ErrorContains(t, cfg.Validate(), "http3.addr")
+
+		cfg.HTTP3.Addr = "127.0.0.1:8443"
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, "127.0.0.1:8443", cfg.HTTP3Addr())
+	})
+
+	t.Run("should not require certificates without TLS listeners", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Mode = config.ModeH2C
+		cfg.HTTP3.Enabled = false
+		cfg.TLS.CertFile, cfg.TLS.KeyFile = "", ""
+
+		assert.NoError(t, cfg.Validate())
+		assert.False(t, cfg.UsesTLS())
+	})
+
+	t.Run("should reject unknown modes", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Mode = "quic"
+
+		assert.ErrorContains(t, cfg.Validate(), "server.mode")
+	})
+}
+
+func TestValidateListeners(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should allow an empty address with a Unix domain socket", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Addr = ""
+		cfg.Server.Mode = config.ModeH2C
+		cfg.Server.UnixSocket = "/run/basic/basic.sock"
+		cfg.HTTP3.Enabled = false
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should require at least one listener", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Addr = ""
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "server.addr: must be set")
+		assert.ErrorContains(t, err, "http3.addr")
+
+		cfg.Server.SocketActivation = true
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("should parse octal socket permissions", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.UnixSocketMode = "0600"
+
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, os.FileMode(0o600), cfg.Server.UnixSocketPermissions())
+
+		for _, mode := range []string{"rw-rw----", "0999", "01777", ""} {
+			cfg.Server.UnixSocketMode = mode
+			assert.ErrorContains(t, cfg.Validate(), "server.unix_socket_mode", mode)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should round-trip through a config file", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Addr = "0.0.0.0:8443"
+		cfg.Background.ProgressInterval = 750 * time.Millisecond
+
+		var out bytes.Buffer
+		require.NoError(t, cfg.Write(&out))
+		assert.Contains(t, out.String(), "progress_interval: 750ms")
+
+		loaded, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", writeFile(t, out.String())}, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, cfg, loaded)
+	})
+}
@@ -0,0 +1,263 @@
+// Package health reports the gRPC health of the service based on its actual state:
+// NOT_SERVING while warming up and draining, and whenever an internal component
+// such as a saturated worker pool or a failing dependency degrades a service.
+// Unlike grpchealth.StaticChecker it supports the Watch streaming RPC.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// StatusServiceUnknown is sent to watchers of services that are not registered,
+// matching SERVICE_UNKNOWN of the gRPC health protocol.
+const StatusServiceUnknown grpchealth.Status = 3
+
+// watchMethodName is the Watch method of the health protocol as registered by
+// grpchealth, which uses its own proto package to avoid conflicts with grpc-go.
+// Messages are identical on the wire to grpc.health.v1.
+const watchMethodName protoreflect.FullName = "connectext.grpc.health.v1.Health.Watch"
+
+// Checker is a grpchealth.Checker whose statuses follow the lifecycle of the
+// server. All registered services, including the server as a whole (""), start
+// as NOT_SERVING until Ready is called and return to NOT_SERVING for good once
+// Shutdown is called. In between, a service is NOT_SERVING while any component
+// degrades it or the server as a whole.
+type Checker struct {
+	mu       sync.Mutex
+	services map[string]bool
+	ready    bool
+	shutdown bool
+	degraded map[string]map[string]string // Reasons by component by service
+	watchers map[string]map[*watcher]struct{}
+}
+
+// watcher holds the latest status not yet consumed by a Watch call.
+type watcher struct {
+	updates chan grpchealth.Status
+}
+
+// NewChecker returns a Checker for the server as a whole and the given services.
+func NewChecker(services ...string) *Checker {
+	c := &Checker{
+		services: map[string]bool{"": true},
+		degraded: map[string]map[string]string{},
+		watchers: map[string]map[*watcher]struct{}{},
+	}
+	for _, service := range services {
+		c.services[service] = true
+	}
+	return c
+}
+
+// Ready ends the warm-up phase. Services report SERVING unless degraded.
+func (c *Checker) Ready() {
+	c.update(func() { c.ready = true })
+}
+
+// Shutdown switches every service to NOT_SERVING so load balancers stop routing
+// new calls while active streams drain. It cannot be undone. The channels of
+// all watches are closed after their final status, so Watch streams end and do
+// not hold up the drain.
+func (c *Checker) Shutdown() {
+	c.update(func() { c.shutdown = true })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for service, watchers := range c.watchers {
+		for w := range watchers {
+			close(w.updates)
+		}
+		delete(c.watchers, service)
+	}
+}
+
+// Degrade marks service as NOT_SERVING on behalf of component until Recover is
+// called for the same component. Degrading the empty service name affects every
+// service. Calling Degrade again for the same component updates the reason.
+func (c *Checker) Degrade(service, component, reason string) {
+	c.update(func() {
+		if c.degraded[service] == nil {
+			c.degraded[service] = map[string]string{}
+		}
+		c.degraded[service][component] = reason
+	})
+}
+
+// Recover removes the degradation of service reported by component.
+func (c *Checker) Recover(service, component string) {
+	c.update(func() {
+		delete(c.degraded[service], component)
+	})
+}
+
+// Reasons returns why service is not serving, one entry per degrading component
+// in the form "component: reason", or nil if it is not degraded.
+func (c *Checker) Reasons(service string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reasons []string
+	for _, degraded := range []map[string]string{c.degraded[""], c.degraded[service]} {
+		for component, reason := range degraded {
+			reasons = append(reasons, component+": "+reason)
+		}
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// Check implements grpchealth.Checker. Unknown services result in CodeNotFound.
+func (c *Checker) Check(_ context.Context, req *grpchealth.CheckRequest) (*grpchealth.CheckResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.services[req.Service] {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("unknown service %s", req.Service))
+	}
+	return &grpchealth.CheckResponse{Status: c.status(req.Service)}, nil
+}
+
+// Watch returns a channel receiving the current status of service immediately
+// and then every change. Slow receivers only see the latest status. Unknown
+// services report StatusServiceUnknown. The channel is closed after the final
+// NOT_SERVING once Shutdown is called. The returned function stops the watch.
+func (c *Checker) Watch(service string) (<-chan grpchealth.Status, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &watcher{updates: make(chan grpchealth.Status, 1)}
+	w.updates <- c.status(service)
+	if c.shutdown {
+		close(w.updates)
+		return w.updates, func() {}
+	}
+	if c.watchers[service] == nil {
+		c.watchers[service] = map[*watcher]struct{}{}
+	}
+	c.watchers[service][w] = struct{}{}
+
+	return w.updates, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.watchers[service], w)
+	}
+}
+
+// update applies change and notifies the watchers of every service whose
+// status changed.
+func (c *Checker) update(change func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	before := make(map[string]grpchealth.Status, len(c.watchers))
+	for service := range c.watchers {
+		before[service] = c.status(service)
+	}
+
+	change()
+
+	for service, watchers := range c.watchers {
+		status := c.status(service)
+		if status == before[service] {
+			continue
+		}
+		for w := range watchers {
+			// Replace an unconsumed status so the channel never blocks
+			select {
+			case <-w.updates:
+			default:
+			}
+			w.updates <- status
+		}
+	}
+}
+
+// status computes the status of service. It must be called with c.mu held.
+func (c *Checker) status(service string) grpchealth.Status {
+	switch {
+	case !c.services[service]:
+		return StatusServiceUnknown
+	case !c.ready || c.shutdown:
+		return grpchealth.StatusNotServing
+	case len(c.degraded[""]) > 0 || len(c.degraded[service]) > 0:
+		return grpchealth.StatusNotServing
+	default:
+		return grpchealth.StatusServing
+	}
+}
+
+// NewHandler returns the grpc.health.v1.Health service backed by checker,
+// including the Watch RPC which grpchealth.NewHandler leaves unimplemented.
+// Watch streams the current status and then every transition until the client
+// disconnects or the checker is shut down.
+func NewHandler(checker *Checker, options ...connect.HandlerOption) (string, http.Handler) {
+	path, handler := grpchealth.NewHandler(checker, options...)
+
+	method, err := watchMethod()
+	if err != nil {
+		// The descriptor is registered by grpchealth, so this is a programming error
+		panic(err)
+	}
+	statusField := method.Output().Fields().ByName("status")
+	serviceField := method.Input().Fields().ByName("service")
+
+	watch := connect.NewServerStreamHandler(
+		path+"Watch",
+		func(ctx context.Context, req *connect.Request[dynamicpb.Message], stream *connect.ServerStream[dynamicpb.Message]) error {
+			updates, stop := checker.Watch(req.Msg.Get(serviceField).String())
+			defer stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case status, ok := <-updates:
+					if !ok {
+						return nil // Shut down, NOT_SERVING was sent
+					}
+					resp := dynamicpb.NewMessage(method.Output())
+					resp.Set(statusField, protoreflect.ValueOfEnum(protoreflect.EnumNumber(status)))
+					if err := stream.Send(resp); err != nil {
+						return err
+					}
+				}
+			}
+		},
+		append([]connect.HandlerOption{
+			connect.WithSchema(method),
+			connect.WithRequestInitializer(func(_ connect.Spec, msg any) error {
+				*msg.(*dynamicpb.Message) = *dynamicpb.NewMessage(method.Input())
+				return nil
+			}),
+		}, options...)...,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	mux.Handle(path+"Watch", watch)
+	return path, mux
+}
+
+// watchMethod returns the descriptor of the health Watch method registered by
+// grpchealth.
+func watchMethod() (protoreflect.MethodDescriptor, error) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(watchMethodName)
+	if err != nil {
+		return nil, fmt.Errorf("find %s: %w", watchMethodName, err)
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T, not a method", watchMethodName, desc)
+	}
+	return method, nil
+}
@@ -0,0 +1,260 @@
+package health_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const service = "basic.v1.BasicService"
+
+// status checks service and returns its status.
+func status(t *testing.T, checker *health.Checker, service string) grpchealth.Status {
+	t.Helper()
+
+	resp, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+// next returns the next status sent on updates.
+func next(t *testing.T, updates <-chan grpchealth.Status) grpchealth.Status {
+	t.Helper()
+
+	select {
+	case status := <-updates:
+		return status
+	case <-time.After(5 * time.Second):
+		t.Fatal("no status update received")
+		return grpchealth.StatusUnknown
+	}
+}
+
+func TestChecker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should not serve during warm-up", func(t *testing.T) {
+		checker := health.NewChecker(service)
+
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, ""))
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, service))
+
+		checker.Ready()
+		assert.Equal(t, grpchealth.StatusServing, status(t, checker, ""))
+		assert.Equal(t, grpchealth.StatusServing, status(t, checker, service))
+	})
+
+	t.Run("should not serve after shutdown", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Ready()
+
+		checker.Shutdown()
+		checker.Ready()
+
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, ""))
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, service))
+	})
+
+	t.Run("should not serve while degraded by any component", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Ready()
+
+		checker.Degrade(service, "background", "10 jobs running")
+		checker.Degrade(service, "downstream", "service-1 unavailable")
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, service))
+		assert.Equal(t, grpchealth.StatusServing, status(t, checker, ""))
+		assert.Equal(t, []string{"background: 10 jobs running", "downstream: service-1 unavailable"}, checker.Reasons(service))
+
+		checker.Recover(service, "background")
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, service))
+
+		checker.Recover(service, "downstream")
+		assert.Equal(t, grpchealth.StatusServing, status(t, checker, service))
+		assert.Empty(t, checker.Reasons(service))
+	})
+
+	t.Run("should degrade every service when the server is degraded", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Ready()
+
+		checker.Degrade("", "database", "connection refused")
+
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, ""))
+		assert.Equal(t, grpchealth.StatusNotServing, status(t, checker, service))
+		assert.Equal(t, []string{"database: connection refused"}, checker.Reasons(service))
+	})
+
+	t.Run("should reject unknown services", func(t *testing.T) {
+		checker := health.NewChecker(service)
+
+		_, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: "unknown.v1.Service"})
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+	})
+}
+
+func TestCheckerWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should send the current status and every transition", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		updates, stop := checker.Watch(service)
+		defer stop()
+
+		assert.Equal(t, grpchealth.StatusNotServing, next(t, updates))
+
+		checker.Ready()
+		assert.Equal(t, grpchealth.StatusServing, next(t, updates))
+
+		checker.Degrade(service, "background", "saturated")
+		assert.Equal(t, grpchealth.StatusNotServing, next(t, updates))
+
+		checker.Recover(service, "background")
+		assert.Equal(t, grpchealth.StatusServing, next(t, updates))
+	})
+
+	t.Run("should not send updates without a transition", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Ready()
+		updates, stop := checker.Watch(service)
+		defer stop()
+		next(t, updates)
+
+		checker.Degrade("other.v1.Service", "background", "saturated")
+		checker.Ready()
+
+		select {
+		case status := <-updates:
+			t.Fatalf("unexpected update %s", status)
+		default:
+		}
+	})
+
+	t.Run("should only keep the latest status for slow receivers", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		updates, stop := checker.Watch(service)
+		defer stop()
+
+		checker.Ready()
+		checker.Degrade(service, "background", "saturated")
+
+		assert.Equal(t, grpchealth.StatusNotServing, next(t, updates))
+		select {
+		case status := <-updates:
+			t.Fatalf("unexpected update %s", status)
+		default:
+		}
+	})
+
+	t.Run("should report unknown services", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		updates, stop := checker.Watch("unknown.v1.Service")
+		defer stop()
+
+		assert.Equal(t, health.StatusServiceUnknown, next(t, updates))
+	})
+
+	t.Run("should close watches after the final status on shutdown", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Ready()
+		updates, stop := checker.Watch(service)
+		defer stop()
+		next(t, updates)
+
+		checker.Shutdown()
+
+		assert.Equal(t, grpchealth.StatusNotServing, next(t, updates))
+		_, open := <-updates
+		assert.False(t, open)
+	})
+
+	t.Run("should close watches started after shutdown", func(t *testing.T) {
+		checker := health.NewChecker(service)
+		checker.Shutdown()
+		updates, stop := checker.Watch(service)
+		defer stop()
+
+		assert.Equal(t, grpchealth.StatusNotServing, next(t, updates))
+		_, open := <-updates
+		assert.False(t, open)
+	})
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName("connectext.grpc.health.v1.Health.Watch")
+	require.NoError(t, err)
+	method := desc.(protoreflect.MethodDescriptor)
+
+	checker := health.NewChecker(service)
+	mux := http.NewServeMux()
+	mux.Handle(health.NewHandler(checker))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	t.Run("should serve Check", func(t *testing.T) {
+		client := connect.NewClient[dynamicpb.Message, dynamicpb.Message](
+			server.Client(),
+			server.URL+"/grpc.health.v1.Health/Check",
+			connect.WithGRPC(),
+			connect.WithResponseInitializer(func(_ connect.Spec, msg any) error {
+				*msg.(*dynamicpb.Message) = *dynamicpb.NewMessage(method.Output())
+				return nil
+			}),
+		)
+		req := dynamicpb.NewMessage(method.Input())
+		req.Set(method.Input().Fields().ByName("service"), protoreflect.ValueOfString(service))
+
+		resp, err := client.CallUnary(context.Background(), connect.NewRequest(req))
+		require.NoError(t, err)
+		assert.EqualValues(t, grpchealth.StatusNotServing, resp.Msg.Get(method.Output().Fields().ByName("status")).Enum())
+	})
+
+	t.Run("should stream transitions from Watch", func(t *testing.T) {
+		client := connect.NewClient[dynamicpb.Message, dynamicpb.Message](
+			server.Client(),
+			server.URL+"/grpc.health.v1.Health/Watch",
+			connect.WithGRPC(),
+			connect.WithSchema(method),
+			connect.WithResponseInitializer(func(_ connect.Spec, msg any) error {
+				*msg.(*dynamicpb.Message) = *dynamicpb.NewMessage(method.Output())
+				return nil
+			}),
+		)
+		req := dynamicpb.NewMessage(method.Input())
+		req.Set(method.Input().Fields().ByName("service"), protoreflect.ValueOfString(service))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.CallServerStream(ctx, connect.NewRequest(req))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		statusField := method.Output().Fields().ByName("status")
+		receive := func() grpchealth.Status {
+			require.True(t, stream.Receive(), "stream ended: %v", stream.Err())
+			return grpchealth.Status(stream.Msg().Get(statusField).Enum())
+		}
+
+		assert.Equal(t, grpchealth.StatusNotServing, receive())
+		checker.Ready()
+		assert.Equal(t, grpchealth.StatusServing, receive())
+		checker.Shutdown()
+		assert.Equal(t, grpchealth.StatusNotServing, receive())
+		assert.False(t, stream.Receive(), "stream ends after shutdown")
+		assert.NoError(t, stream.Err())
+	})
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// defaultPageSize is the number of jobs ListBackgroundJobs returns if the
+// request does not ask for a page size.
+const defaultPageSize = 50
+
+// job is a Background job. Its state and results are tracked by the
+// StateManager of the service under its ID.
+type job struct {
+	id      string
+	owner   string                 // Client that started the job as keyed by ownerOf, the only one allowed to access it
+	targets []config.ServiceConfig // Service called by every process, by process index
+	cancel  context.CancelFunc     // Cancels the downstream calls of the job
+	done    chan struct{}          // Closed once the job ended
+}
+
+// newJob returns the job id started by owner calling targets, one process each.
+// cancel cancels the context of the downstream calls.
+func newJob(id, owner string, targets []config.ServiceConfig, cancel context.CancelFunc) *job {
+	return &job{id: id, owner: owner, targets: targets, cancel: cancel, done: make(chan struct{})}
+}
+
+// job returns the job with id, or nil if there is none.
+func (s *BasicServiceV1) job(id string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// forget removes the job with id, evicted by the StateManager once it finished.
+func (s *BasicServiceV1) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+// errJobNotFound returns the error reporting that there is no job with id.
+func errJobNotFound(id string) error {
+	return rpcerror.New(connect.CodeNotFound, basicerrors.ReasonJobNotFound, fmt.Errorf("job %s not found", id), "job_id", id)
+}
+
+// ownedJob returns the job with id if it was started by the client of ctx and
+// peer, or a NotFound error otherwise.
+func (s *BasicServiceV1) ownedJob(ctx context.Context, peer connect.Peer, id string) (*job, error) {
+	j := s.job(id)
+	if j == nil || j.owner != ownerOf(ctx, peer) {
+		// Jobs of other callers are reported as missing to not reveal them.
+		return nil, errJobNotFound(id)
+	}
+	return j, nil
+}
+
+// event returns the status update reporting the progress of j as tracked by
+// the StateManager. Processes without a recorded result are still running, or
+// share the final state of j if it ended early, e.g. by a panic. It returns
+// false if the StateManager evicted j since it was looked up.
+func (s *BasicServiceV1) event(j *job) (*basicServiceV1.BackgroundResponseEvent, bool) {
+	state, start, finish := s.StateManager.GetState(j.id)
+	if state == nil {
+		return nil, false
+	}
+
+	results := make([]*basicServiceV1.ProcessResult, len(j.targets))
+	for i, target := range j.targets {
+		results[i] = &basicServiceV1.ProcessResult{
+			Process: int64(i),
+			Service: target.Name,
+			Type:    target.Type,
+			State:   *state,
+		}
+	}
+	responses := []*basicServiceV1.SomeServiceResponse{}
+	for _, result := range s.StateManager.Results(j.id) {
+		results[result.Process] = result
+		responses = append(responses, result.Responses...)
+	}
+
+	return &basicServiceV1.BackgroundResponseEvent{
+		State:       *state,
+		StartedAt:   start,
+		CompletedAt: finish,
+		Responses:   responses,
+		Results:     results,
+		JobId:       j.id,
+	}, true
+}
+
+// describe returns the current state of j as tracked by the StateManager, or
+// false if it was evicted since it was looked up.
+func (s *BasicServiceV1) describe(j *job) (*basicServiceV1.BackgroundJob, bool) {
+	event, ok := s.event(j)
+	if !ok {
+		return nil, false
+	}
+
+	errs := s.StateManager.GetErrors(j.id)
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return &basicServiceV1.BackgroundJob{
+		JobId:       j.id,
+		State:       event.State,
+		StartedAt:   event.StartedAt,
+		CompletedAt: event.CompletedAt,
+		Results:     event.Results,
+		Errors:      messages,
+	}, true
+}
+
+// ListBackgroundJobs returns the Background jobs started by the caller ordered by
+// start time, a page at a time. Jobs can be filtered by state.
+func (s *BasicServiceV1) ListBackgroundJobs(ctx context.Context, req *connect.Request[basicServiceV1.ListBackgroundJobsRequest]) (*connect.Response[basicServiceV1.ListBackgroundJobsResponse], error) {
+	pageSize := int(req.Msg.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	after, err := parsePageToken(req.Msg.PageToken)
+	if err != nil {
+		connectErr := rpcerror.New(connect.CodeInvalidArgument, basicerrors.ReasonInvalidRequest, fmt.Errorf("invalid ListBackgroundJobsRequest: page_token: %w", err))
+		return nil, rpcerror.BadRequest(connectErr, &errdetails.BadRequest_FieldViolation{
+			Field:       "page_token",
+			Description: "must be the next_page_token of a previous response",
+			Reason:      "page_token",
+		})
+	}
+
+	owner := ownerOf(ctx, req.Peer())
+	resp := &basicServiceV1.ListBackgroundJobsResponse{}
+	for _, snapshot := range s.StateManager.Snapshot() {
+		if after != nil && !after.before(snapshot) {
+			continue
+		}
+		state := basicServiceV1.State(basicServiceV1.State_value[snapshot.State])
+		if len(req.Msg.States) > 0 && !slices.Contains(req.Msg.States, state) {
+			continue
+		}
+		j := s.job(snapshot.Hash)
+		if j == nil || j.owner != owner {
+			continue
+		}
+
+		if len(resp.Jobs) == pageSize {
+			resp.NextPageToken = pageTokenOf(resp.Jobs[len(resp.Jobs)-1])
+			break
+		}
+		if described, ok := s.describe(j); ok {
+			resp.Jobs = append(resp.Jobs, described)
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// GetBackgroundJob returns a Background job started by the caller.
+func (s *BasicServiceV1) GetBackgroundJob(ctx context.Context, req *connect.Request[basicServiceV1.GetBackgroundJobRequest]) (*connect.Response[basicServiceV1.GetBackgroundJobResponse], error) {
+	j, err := s.ownedJob(ctx, req.Peer(), req.Msg.JobId)
+	if err != nil {
+		return nil, err
+	}
+	described, ok := s.describe(j)
+	if !ok {
+		return nil, errJobNotFound(j.id)
+	}
+	return connect.NewResponse(&basicServiceV1.GetBackgroundJobResponse{Job: described}), nil
+}
+
+// CancelBackgroundJob cancels a running Background job started by the caller and
+// waits until its in-flight downstream calls stopped. Cancelling a cancelled job
+// returns it unchanged, jobs that already finished otherwise cannot be cancelled,
+// including jobs whose calls all returned before the cancellation reached them.
+func (s *BasicServiceV1) CancelBackgroundJob(ctx context.Context, req *connect.Request[basicServiceV1.CancelBackgroundJobRequest]) (*connect.Response[basicServiceV1.CancelBackgroundJobResponse], error) {
+	j, err := s.ownedJob(ctx, req.Peer(), req.Msg.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _, _ := s.StateManager.GetState(j.id)
+	if state == nil {
+		return nil, errJobNotFound(j.id)
+	}
+	switch *state {
+	case basicServiceV1.State_STATE_PROCESS:
+		slog.InfoContext(ctx, "Background cancelled", "hash", j.id, "caller", j.owner)
+		j.cancel()
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if state, _, _ = s.StateManager.GetState(j.id); state == nil {
+			return nil, errJobNotFound(j.id)
+		}
+	}
+	if *state != basicServiceV1.State_STATE_CANCELLED {
+		return nil, rpcerror.New(connect.CodeFailedPrecondition, basicerrors.ReasonJobFinished,
+			fmt.Errorf("job %s already finished in %s", j.id, state), "job_id", j.id, "state", state.String())
+	}
+
+	described, ok := s.describe(j)
+	if !ok {
+		return nil, errJobNotFound(j.id)
+	}
+	return connect.NewResponse(&basicServiceV1.CancelBackgroundJobResponse{Job: described}), nil
+}
+
+// pageToken is the position of the last job of a page in the order of
+// StateManager.Snapshot, by start time and hash.
+type pageToken struct {
+	start time.Time
+	hash  string
+}
+
+// pageTokenOf returns the token of the page following job.
+func pageTokenOf(job *basicServiceV1.BackgroundJob) string {
+	token := strconv.FormatInt(job.GetStartedAt().AsTime().UnixNano(), 10) + "/" + job.GetJobId()
+	return base64.RawURLEncoding.EncodeToString([]byte(token))
+}
+
+// parsePageToken returns the position encoded in token, or nil for the first
+// page.
+func parsePageToken(token string) (*pageToken, error) {
+	if token == "" {
+		return nil, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	nanos, hash, ok := strings.Cut(string(decoded), "/")
+	if !ok {
+		return nil, fmt.Errorf("malformed token")
+	}
+	start, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	return &pageToken{start: time.Unix(0, start), hash: hash}, nil
+}
+
+// before reports whether the position of t comes before snapshot.
+func (t *pageToken) before(snapshot utils.Snapshot) bool {
+	if snapshot.Start == nil || snapshot.Start.Equal(t.start) {
+		return t.hash < snapshot.Hash
+	}
+	return t.start.Before(*snapshot.Start)
+}
@@ -0,0 +1,228 @@
+package internal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundJobs(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default().Background
+	cfg.ProgressInterval = 10 * time.Millisecond
+	client := newClient(t, cfg)
+	ctx := context.Background()
+
+	// Enough processes that at least one call is still running when cancelled.
+	first := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+	second := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+
+	t.Run("should get a job by ID", func(t *testing.T) {
+		resp, err := client.GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: first}))
+		require.NoError(t, err)
+
+		assert.Equal(t, first, resp.Msg.GetJob().GetJobId())
+		assert.Len(t, resp.Msg.GetJob().GetResults(), 10)
+		assert.NotNil(t, resp.Msg.GetJob().GetStartedAt())
+	})
+
+	t.Run("should report unknown jobs as not found", func(t *testing.T) {
+		_, err := client.GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: uuid.NewString()}))
+
+		details, ok := basicerrors.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeNotFound, details.Code)
+		assert.Equal(t, basicerrors.ReasonJobNotFound, details.Reason)
+	})
+
+	t.Run("should list jobs by start time a page at a time", func(t *testing.T) {
+		page, err := client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{PageSize: 1}))
+		require.NoError(t, err)
+		require.Len(t, page.Msg.GetJobs(), 1)
+		assert.Equal(t, first, page.Msg.GetJobs()[0].GetJobId())
+		require.NotEmpty(t, page.Msg.GetNextPageToken())
+
+		page, err = client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{PageSize: 1, PageToken: page.Msg.GetNextPageToken()}))
+		require.NoError(t, err)
+		require.Len(t, page.Msg.GetJobs(), 1)
+		assert.Equal(t, second, page.Msg.GetJobs()[0].GetJobId())
+		assert.Empty(t, page.Msg.GetNextPageToken())
+	})
+
+	t.Run("should reject malformed page tokens", func(t *testing.T) {
+		_, err := client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{PageToken: "not a token"}))
+
+		details, ok := basicerrors.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeInvalidArgument, details.Code)
+		require.Len(t, details.FieldViolations, 1)
+		assert.Equal(t, "page_token", details.FieldViolations[0].GetField())
+	})
+
+	t.Run("should cancel running jobs and their downstream calls", func(t *testing.T) {
+		resp, err := client.CancelBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: first}))
+		require.NoError(t, err)
+
+		job := resp.Msg.GetJob()
+		assert.Equal(t, basicServiceV1.State_STATE_CANCELLED, job.GetState())
+		assert.NotNil(t, job.GetCompletedAt())
+		var cancelled int
+		for _, result := range job.GetResults() {
+			assert.NotEqual(t, basicServiceV1.State_STATE_PROCESS, result.GetState())
+			if result.GetState() == basicServiceV1.State_STATE_CANCELLED {
+				cancelled++
+				assert.Empty(t, result.GetResponses())
+			}
+		}
+		assert.Positive(t, cancelled)
+		assert.Empty(t, job.GetErrors())
+	})
+
+	t.Run("should return cancelled jobs unchanged when cancelled again", func(t *testing.T) {
+		resp, err := client.CancelBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: first}))
+		require.NoError(t, err)
+
+		assert.Equal(t, basicServiceV1.State_STATE_CANCELLED, resp.Msg.GetJob().GetState())
+	})
+
+	t.Run("should filter jobs by state", func(t *testing.T) {
+		resp, err := client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{
+			States: []basicServiceV1.State{basicServiceV1.State_STATE_CANCELLED},
+		}))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Msg.GetJobs(), 1)
+		assert.Equal(t, first, resp.Msg.GetJobs()[0].GetJobId())
+	})
+
+	t.Run("should end the stream of cancelled jobs with the final state", func(t *testing.T) {
+		event := firstEvent(t, client, &basicServiceV1.BackgroundRequest{JobId: first})
+
+		assert.Equal(t, basicServiceV1.State_STATE_CANCELLED, event.GetState())
+	})
+}
+
+func TestBackgroundJobRetention(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default().Background
+	cfg.MaxFinishedJobs = 1
+	client := newClient(t, cfg)
+	ctx := context.Background()
+
+	// Cancelling finishes the jobs right away instead of waiting for the calls.
+	var ids []string
+	for range 2 {
+		id := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+		_, err := client.CancelBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: id}))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	t.Run("should report evicted jobs as not found", func(t *testing.T) {
+		_, err := client.GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: ids[0]}))
+
+		details, ok := basicerrors.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeNotFound, details.Code)
+		assert.Equal(t, basicerrors.ReasonJobNotFound, details.Reason)
+	})
+
+	t.Run("should not reattach to evicted jobs", func(t *testing.T) {
+		stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{JobId: ids[0]}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(stream.Err()))
+	})
+
+	t.Run("should keep the most recently finished jobs", func(t *testing.T) {
+		resp, err := client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{}))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Msg.GetJobs(), 1)
+		assert.Equal(t, ids[1], resp.Msg.GetJobs()[0].GetJobId())
+	})
+}
+
+func TestCancelBackgroundJobAfterLastCall(t *testing.T) {
+	t.Parallel()
+
+	// The call answers only once cancelled, like a call that returned just
+	// before the cancellation reached it.
+	service := internal.NewBasicServiceV1(config.Default().Background)
+	service.CallService = func(ctx context.Context, serviceName, _ string) chan *basicServiceV1.SomeServiceResponse {
+		response := make(chan *basicServiceV1.SomeServiceResponse, 1)
+		go func() {
+			defer close(response)
+			<-ctx.Done()
+			response <- &basicServiceV1.SomeServiceResponse{Id: uuid.NewString(), Name: serviceName}
+		}()
+		return response
+	}
+	client := serve(t, service)
+	ctx := context.Background()
+	id := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 1}).GetJobId()
+
+	t.Run("should not report completed jobs as cancelled", func(t *testing.T) {
+		_, err := client.CancelBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: id}))
+
+		details, ok := basicerrors.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeFailedPrecondition, details.Code)
+		assert.Equal(t, basicerrors.ReasonJobFinished, details.Reason)
+
+		resp, err := client.GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: id}))
+		require.NoError(t, err)
+		assert.Equal(t, basicServiceV1.State_STATE_COMPLETE, resp.Msg.GetJob().GetState())
+	})
+}
+
+func TestBackgroundJobsOfOtherPeers(t *testing.T) {
+	t.Parallel()
+
+	clients := serveClients(t, internal.NewBasicServiceV1(config.Default().Background), "127.0.0.1", "127.0.0.2")
+	ctx := context.Background()
+	id := firstEvent(t, clients[0], &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+
+	t.Run("should not list jobs of other peers", func(t *testing.T) {
+		resp, err := clients[1].ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{}))
+		require.NoError(t, err)
+
+		assert.Empty(t, resp.Msg.GetJobs())
+	})
+
+	t.Run("should not get jobs of other peers", func(t *testing.T) {
+		_, err := clients[1].GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: id}))
+
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+	})
+
+	t.Run("should not cancel jobs of other peers", func(t *testing.T) {
+		_, err := clients[1].CancelBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: id}))
+
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+		resp, err := clients[0].GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: id}))
+		require.NoError(t, err)
+		assert.Equal(t, basicServiceV1.State_STATE_PROCESS, resp.Msg.GetJob().GetState())
+	})
+
+	t.Run("should list jobs of the same peer", func(t *testing.T) {
+		resp, err := clients[0].ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{}))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Msg.GetJobs(), 1)
+		assert.Equal(t, id, resp.Msg.GetJobs()[0].GetJobId())
+	})
+}
@@ -0,0 +1,179 @@
+// Package listeners opens the sockets the servers accept connections on besides
+// their TCP and UDP bind addresses: Unix domain sockets for sidecars and local IPC,
+// and sockets passed in by systemd socket activation.
+package listeners
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd, following stdin,
+// stdout and stderr.
+const listenFDsStart = 3
+
+// Set groups stream listeners served by the HTTP/2 server and datagram sockets
+// served by the HTTP/3 server.
+type Set struct {
+	Stream []net.Listener
+	Packet []net.PacketConn
+}
+
+// Add appends the sockets of other to the set.
+func (s *Set) Add(other *Set) {
+	s.Stream = append(s.Stream, other.Stream...)
+	s.Packet = append(s.Packet, other.Packet...)
+}
+
+// Close closes every socket in the set. It is used to release sockets that were
+// opened but never handed to a server.
+func (s *Set) Close() {
+	for _, l := range s.Stream {
+		l.Close()
+	}
+	for _, conn := range s.Packet {
+		conn.Close()
+	}
+}
+
+// Unix listens on the Unix domain socket at path and applies the file permissions
+// mode to it. A stale socket left behind by a previous run is removed first, but
+// any other file at path is left untouched and reported as an error. The socket
+// file is removed again when the listener is closed.
+func Unix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("listen on unix socket %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale unix socket: %w", err)
+		}
+	}
+
+	// The socket is created with the permissions of the umask. Create it in a
+	// private directory and only move it into place once mode is applied, so
+	// no other user can connect in between.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".unix-")
+	if err != nil {
+		return nil, fmt.Errorf("create directory of unix socket %s: %w", path, err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, "sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: private, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("listen on unix socket %s: %w", path, err)
+	}
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(private, mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("set permissions of unix socket %s: %w", path, err)
+	}
+	if err := os.Rename(private, path); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("move unix socket into place at %s: %w", path, err)
+	}
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+// unixListener is a Unix domain socket listener moved to path after it was
+// created, which it reports as its address and removes once closed.
+type unixListener struct {
+	*net.UnixListener
+	path   string
+	remove sync.Once
+}
+
+// Addr implements net.Listener.
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close implements net.Listener.
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.remove.Do(func() { os.Remove(l.path) })
+	return err
+}
+
+// Systemd returns the sockets passed to the process by systemd socket activation
+// (LISTEN_PID and LISTEN_FDS). Stream sockets become listeners, datagram sockets
+// packet connections. The environment variables are cleared so child processes
+// do not inherit them. An empty set is returned if the process was not socket
+// activated.
+func Systemd() (*Set, error) {
+	count, err := listenFDs(os.LookupEnv, os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	files := make([]*os.File, count)
+	for i := range files {
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), "LISTEN_FD_"+strconv.Itoa(listenFDsStart+i))
+	}
+	return fromFiles(files)
+}
+
+// listenFDs returns the number of sockets systemd passed to the process with the
+// given pid, or 0 if the sockets were meant for a different process.
+func listenFDs(lookupEnv func(string) (string, bool), pid int) (int, error) {
+	pidValue, ok := lookupEnv("LISTEN_PID")
+	if !ok {
+		return 0, nil
+	}
+	listenPID, err := strconv.Atoi(strings.TrimSpace(pidValue))
+	if err != nil {
+		return 0, fmt.Errorf("LISTEN_PID: invalid value %q: %w", pidValue, err)
+	}
+	if listenPID != pid {
+		return 0, nil
+	}
+
+	fdsValue, _ := lookupEnv("LISTEN_FDS")
+	count, err := strconv.Atoi(strings.TrimSpace(fdsValue))
+	if err != nil {
+		return 0, fmt.Errorf("LISTEN_FDS: invalid value %q: %w", fdsValue, err)
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("LISTEN_FDS: must not be negative, got %d", count)
+	}
+	return count, nil
+}
+
+// fromFiles converts inherited socket files into listeners and packet
+// connections. The files are closed as the returned sockets hold duplicates of
+// their descriptors. On error every socket opened so far is closed.
+func fromFiles(files []*os.File) (*Set, error) {
+	set := &Set{}
+	for i, f := range files {
+		l, listenErr := net.FileListener(f)
+		if listenErr == nil {
+			set.Stream = append(set.Stream, l)
+			f.Close()
+			continue
+		}
+		conn, packetErr := net.FilePacketConn(f)
+		if packetErr == nil {
+			set.Packet = append(set.Packet, conn)
+			f.Close()
+			continue
+		}
+
+		set.Close()
+		for _, rest := range files[i:] {
+			rest.Close()
+		}
+		return nil, fmt.Errorf("inherited socket %s is neither a stream nor a datagram socket: %w", f.Name(), errors.Join(listenErr, packetErr))
+	}
+	return set, nil
+}
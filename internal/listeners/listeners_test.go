@@ -0,0 +1,155 @@
+package listeners
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a lookup function backed by vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestUnix(t *testing.T) {
+	t.Run("should listen with the configured permissions", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "basic.sock")
+
+		l, err := Unix(path, 0o660)
+		require.NoError(t, err)
+		defer l.Close()
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+		conn, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("should only create the socket file at path", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "basic.sock")
+
+		l, err := Unix(path, 0o600)
+		require.NoError(t, err)
+		defer l.Close()
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "the private directory of the socket is removed")
+		assert.Equal(t, "basic.sock", entries[0].Name())
+		assert.Equal(t, path, l.Addr().String())
+	})
+
+	t.Run("should remove the socket file on close", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "basic.sock")
+
+		l, err := Unix(path, 0o600)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("should replace a stale socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "basic.sock")
+		stale, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		l, err := Unix(path, 0o600)
+		require.NoError(t, err)
+		l.Close()
+	})
+
+	t.Run("should refuse to replace other files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "basic.sock")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+		_, err := Unix(path, 0o600)
+		assert.ErrorContains(t, err, "not a socket")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	})
+}
+
+func TestListenFDs(t *testing.T) {
+	t.Run("should return the number of sockets for this process", func(t *testing.T) {
+		count, err := listenFDs(env(map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "2"}), 42)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("should ignore sockets when not socket activated", func(t *testing.T) {
+		count, err := listenFDs(env(nil), 42)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("should ignore sockets meant for another process", func(t *testing.T) {
+		count, err := listenFDs(env(map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "2"}), 42)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("should reject invalid values", func(t *testing.T) {
+		_, err := listenFDs(env(map[string]string{"LISTEN_PID": "self", "LISTEN_FDS": "2"}), 42)
+		assert.ErrorContains(t, err, "LISTEN_PID")
+
+		_, err = listenFDs(env(map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "many"}), 42)
+		assert.ErrorContains(t, err, "LISTEN_FDS")
+
+		_, err = listenFDs(env(map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "-1"}), 42)
+		assert.ErrorContains(t, err, "LISTEN_FDS")
+	})
+}
+
+func TestFromFiles(t *testing.T) {
+	t.Run("should convert stream and datagram sockets", func(t *testing.T) {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer tcp.Close()
+		tcpFile, err := tcp.(*net.TCPListener).File()
+		require.NoError(t, err)
+
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer udp.Close()
+		udpFile, err := udp.(*net.UDPConn).File()
+		require.NoError(t, err)
+
+		set, err := fromFiles([]*os.File{tcpFile, udpFile})
+		require.NoError(t, err)
+		defer set.Close()
+
+		require.Len(t, set.Stream, 1)
+		require.Len(t, set.Packet, 1)
+		assert.Equal(t, tcp.Addr().String(), set.Stream[0].Addr().String())
+		assert.Equal(t, udp.LocalAddr().String(), set.Packet[0].LocalAddr().String())
+
+		conn, err := net.Dial("tcp", tcp.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("should reject files that are not sockets", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "fd")
+		require.NoError(t, err)
+
+		_, err = fromFiles([]*os.File{f})
+		assert.ErrorContains(t, err, "neither a stream nor a datagram socket")
+	})
+}
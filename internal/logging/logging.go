@@ -0,0 +1,92 @@
+// Package logging provides the structured slog logger of the service. Records
+// logged with a request context carry the fields of the call they belong to:
+// request ID, HTTP version, peer address, procedure, protocol and the time
+// elapsed since the request started.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+)
+
+// callKey is the context key for the fields of the current call.
+type callKey struct{}
+
+// call holds the fields attached to every record logged during a call.
+type call struct {
+	start     time.Time
+	requestID string
+	attrs     []slog.Attr
+}
+
+// New returns a logger writing records at or above cfg.Level to w in cfg.Format.
+// Records logged with a context returned by WithAttrs include its fields.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.SlogLevel()}
+
+	var handler slog.Handler
+	if cfg.Format == config.LogFormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// WithAttrs returns a copy of ctx whose records include attrs in addition to the
+// fields already attached to ctx.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	next := &call{}
+	if current, ok := ctx.Value(callKey{}).(*call); ok {
+		*next = *current
+		next.attrs = slices.Clone(current.attrs)
+	}
+	next.attrs = append(next.attrs, attrs...)
+	return context.WithValue(ctx, callKey{}, next)
+}
+
+// startCall returns a copy of ctx for a call that started at start, so its
+// records include the time elapsed since as duration. A non-empty requestID
+// replaces the request ID of ctx.
+func startCall(ctx context.Context, start time.Time, requestID string) context.Context {
+	next := &call{}
+	if current, ok := ctx.Value(callKey{}).(*call); ok {
+		*next = *current
+	}
+	next.start = start
+	if requestID != "" {
+		next.requestID = requestID
+	}
+	return context.WithValue(ctx, callKey{}, next)
+}
+
+// contextHandler adds the fields of the call in the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the call fields and passes the record on.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if c, ok := ctx.Value(callKey{}).(*call); ok {
+		r.AddAttrs(c.attrs...)
+		if !c.start.IsZero() {
+			r.AddAttrs(slog.Duration("duration", time.Since(c.start)))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs keeps the wrapper when attributes are added to the logger.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper when a group is added to the logger.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,200 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// captureDefault replaces the default logger with a JSON logger writing to the
+// returned buffer for the duration of the test.
+func captureDefault(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(config.LogConfig{Level: "debug", Format: config.LogFormatJSON}, &buf))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// records decodes the JSON records written to buf.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		result = append(result, record)
+	}
+	return result
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should write JSON records at or above the configured level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.New(config.LogConfig{Level: "warn", Format: config.LogFormatJSON}, &buf)
+
+		logger.Info("dropped")
+		logger.Warn("kept", "key", "value")
+
+		got := records(t, &buf)
+		require.Len(t, got, 1)
+		assert.Equal(t, "kept", got[0]["msg"])
+		assert.Equal(t, "value", got[0]["key"])
+	})
+
+	t.Run("should write text records", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.New(config.LogConfig{Level: "info", Format: config.LogFormatText}, &buf)
+
+		logger.Info("hello", "key", "value")
+
+		assert.Contains(t, buf.String(), "msg=hello key=value")
+	})
+
+	t.Run("should add the fields of the context", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.New(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &buf).With("component", "test")
+
+		ctx := logging.WithAttrs(context.Background(), slog.String("job", "42"))
+		logger.InfoContext(ctx, "with context")
+		logger.Info("without context")
+
+		got := records(t, &buf)
+		require.Len(t, got, 2)
+		assert.Equal(t, "42", got[0]["job"])
+		assert.Equal(t, "test", got[0]["component"])
+		assert.NotContains(t, got[1], "job")
+	})
+}
+
+func TestHandler(t *testing.T) {
+	handler := logging.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handled")
+		requestID, _ := logging.RequestIDFromContext(r.Context())
+		w.Write([]byte(requestID))
+	}))
+
+	t.Run("should attach request fields to records", func(t *testing.T) {
+		buf := captureDefault(t)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		requestID := rec.Header().Get(logging.RequestIDHeader)
+		assert.NotEmpty(t, requestID)
+		assert.Equal(t, requestID, rec.Body.String())
+
+		got := records(t, buf)
+		require.Len(t, got, 1)
+		assert.Equal(t, requestID, got[0]["request_id"])
+		assert.Equal(t, "http/1.1", got[0]["http_version"])
+		assert.Equal(t, "192.0.2.1:1234", got[0]["peer"])
+		assert.Contains(t, got[0], "duration")
+	})
+
+	t.Run("should keep valid request IDs of the client", func(t *testing.T) {
+		captureDefault(t)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(logging.RequestIDHeader, "upstream-123")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "upstream-123", rec.Header().Get(logging.RequestIDHeader))
+	})
+
+	t.Run("should replace invalid request IDs", func(t *testing.T) {
+		captureDefault(t)
+		for _, id := range []string{"with space", strings.Repeat("x", 129), "line\nbreak"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(logging.RequestIDHeader, id)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEqual(t, id, rec.Header().Get(logging.RequestIDHeader))
+			assert.NotEmpty(t, rec.Header().Get(logging.RequestIDHeader))
+		}
+	})
+}
+
+func TestNewInterceptor(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Ok", connect.NewUnaryHandler(
+		"/test.v1.TestService/Ok",
+		func(ctx context.Context, _ *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			slog.InfoContext(ctx, "inside handler")
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(logging.NewInterceptor()),
+	))
+	mux.Handle("/test.v1.TestService/Fail", connect.NewUnaryHandler(
+		"/test.v1.TestService/Fail",
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return nil, connect.NewError(connect.CodeInternal, errors.New("boom"))
+		},
+		connect.WithInterceptors(logging.NewInterceptor()),
+	))
+	server := httptest.NewServer(logging.Handler(mux))
+	defer server.Close()
+
+	call := func(procedure string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		return err
+	}
+
+	t.Run("should attach call fields to every record", func(t *testing.T) {
+		buf := captureDefault(t)
+
+		require.NoError(t, call("/test.v1.TestService/Ok"))
+
+		got := records(t, buf)
+		require.Len(t, got, 2)
+		for _, record := range got {
+			assert.Equal(t, "/test.v1.TestService/Ok", record["procedure"])
+			assert.Equal(t, "connect", record["protocol"])
+			assert.Equal(t, "http/1.1", record["http_version"])
+			assert.NotEmpty(t, record["request_id"])
+			assert.NotEmpty(t, record["peer"])
+			assert.Contains(t, record, "duration")
+		}
+		assert.Equal(t, "inside handler", got[0]["msg"])
+		assert.Equal(t, "Call finished", got[1]["msg"])
+		assert.Equal(t, "INFO", got[1]["level"])
+	})
+
+	t.Run("should log server errors at error level", func(t *testing.T) {
+		buf := captureDefault(t)
+
+		assert.Error(t, call("/test.v1.TestService/Fail"))
+
+		got := records(t, buf)
+		require.Len(t, got, 1)
+		assert.Equal(t, "Call failed", got[0]["msg"])
+		assert.Equal(t, "ERROR", got[0]["level"])
+		assert.Equal(t, "internal", got[0]["code"])
+		assert.Contains(t, got[0]["error"], "boom")
+	})
+}
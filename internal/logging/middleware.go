@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID. A valid ID sent by the client is kept
+// so calls can be correlated across services, otherwise a new one is generated.
+// The ID is returned to the client in the same header.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds client supplied request IDs.
+const maxRequestIDLength = 128
+
+// Handler attaches the request ID, HTTP version and peer address of every
+// request to its context and starts measuring its duration.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := startCall(r.Context(), time.Now(), requestID)
+		ctx = WithAttrs(ctx,
+			slog.String("request_id", requestID),
+			slog.String("http_version", HTTPVersion(r)),
+			slog.String("peer", r.RemoteAddr),
+		)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request ID attached by Handler.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	c, ok := ctx.Value(callKey{}).(*call)
+	if !ok || c.requestID == "" {
+		return "", false
+	}
+	return c.requestID, true
+}
+
+// validRequestID reports whether id may be used as request ID: non-empty, of
+// bounded length and limited to printable ASCII so it is safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// HTTPVersion returns the short name of the HTTP version of r as used in ALPN:
+// h3, h2, h2c for cleartext HTTP/2 or http/1.1.
+func HTTPVersion(r *http.Request) string {
+	switch {
+	case r.ProtoMajor == 3:
+		return "h3"
+	case r.ProtoMajor == 2 && r.TLS == nil:
+		return "h2c"
+	case r.ProtoMajor == 2:
+		return "h2"
+	default:
+		return "http/1.1"
+	}
+}
+
+// interceptor logs every call with the fields of its context.
+type interceptor struct{}
+
+// NewInterceptor returns a Connect interceptor attaching the procedure and the
+// protocol (grpc, grpcweb or connect) to the context of every call and logging
+// its outcome: successful calls at info level, client errors at warn level and
+// server errors at error level.
+func NewInterceptor() connect.Interceptor {
+	return interceptor{}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx = withCall(ctx, req.Spec(), req.Peer())
+		resp, err := next(ctx, req)
+		logResult(ctx, err)
+		return resp, err
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx = withCall(ctx, conn.Spec(), conn.Peer())
+		err := next(ctx, conn)
+		logResult(ctx, err)
+		return err
+	}
+}
+
+// withCall attaches the procedure and protocol of a call to ctx. Calls that did
+// not pass through Handler are timed from here.
+func withCall(ctx context.Context, spec connect.Spec, peer connect.Peer) context.Context {
+	if c, ok := ctx.Value(callKey{}).(*call); !ok || c.start.IsZero() {
+		ctx = startCall(ctx, time.Now(), "")
+	}
+	return WithAttrs(ctx,
+		slog.String("procedure", spec.Procedure),
+		slog.String("protocol", peer.Protocol),
+	)
+}
+
+// logResult logs the outcome of a call.
+func logResult(ctx context.Context, err error) {
+	if err == nil {
+		slog.InfoContext(ctx, "Call finished", "code", "ok")
+		return
+	}
+
+	code := connect.CodeOf(err)
+	level := slog.LevelWarn
+	if serverError(code) {
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "Call failed", "code", code.String(), "error", err)
+}
+
+// serverError reports whether code indicates a problem of the server rather
+// than of the request.
+func serverError(code connect.Code) bool {
+	switch code {
+	case connect.CodeUnknown, connect.CodeInternal, connect.CodeUnavailable, connect.CodeDataLoss, connect.CodeUnimplemented:
+		return true
+	default:
+		return false
+	}
+}
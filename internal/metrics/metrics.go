@@ -0,0 +1,286 @@
+// Package metrics exposes Prometheus metrics of the service: calls per
+// procedure and status code, call latency, open streams and the messages sent
+// on them, calls rejected by limits, the HTTP version requests arrive over and
+// the background operations tracked and evicted by the StateManager.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+)
+
+// namespace prefixes the names of all metrics of the service.
+const namespace = "basic"
+
+// Metrics holds the collectors of the service in a registry of its own, so
+// several instances, e.g. in tests, do not conflict.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	started          *prometheus.CounterVec
+	handled          *prometheus.CounterVec
+	handlingSeconds  *prometheus.HistogramVec
+	streamsInFlight  *prometheus.GaugeVec
+	messagesReceived *prometheus.CounterVec
+	messagesSent     *prometheus.CounterVec
+	panics           *prometheus.CounterVec
+	limited          *prometheus.CounterVec
+	httpRequests     *prometheus.CounterVec
+}
+
+// New returns the metrics of the service. Background operations are reported
+// from states on every scrape, Go runtime and process metrics are included.
+func New(states *utils.StateManager) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_started_total",
+			Help:      "Calls started by procedure and stream type.",
+		}, []string{"procedure", "type"}),
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_handled_total",
+			Help:      "Calls finished by procedure, stream type and status code.",
+		}, []string{"procedure", "type", "code"}),
+		handlingSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_handling_seconds",
+			Help:      "Time from the start of a call until the handler returned.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10), // 1ms to ~4m for long-lived streams
+		}, []string{"procedure", "type"}),
+		streamsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rpc_streams_in_flight",
+			Help:      "Streams currently open by procedure.",
+		}, []string{"procedure"}),
+		messagesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_stream_messages_received_total",
+			Help:      "Messages received on streams by procedure.",
+		}, []string{"procedure"}),
+		messagesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_stream_messages_sent_total",
+			Help:      "Messages sent on streams by procedure.",
+		}, []string{"procedure"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_panics_total",
+			Help:      "Handler panics recovered by procedure.",
+		}, []string{"procedure"}),
+		limited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_limited_total",
+			Help:      "Calls rejected by rate or stream limits by procedure and limit (rate or streams).",
+		}, []string{"procedure", "limit"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by HTTP version (h3, h2, h2c or http/1.1).",
+		}, []string{"http_version"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.started,
+		m.handled,
+		m.handlingSeconds,
+		m.streamsInFlight,
+		m.messagesReceived,
+		m.messagesSent,
+		m.panics,
+		m.limited,
+		m.httpRequests,
+		newJobsCollector(states),
+	)
+	return m
+}
+
+// Handler returns the Prometheus exposition endpoint of the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// HTTPVersionHandler counts the requests passed to next by HTTP version.
+func (m *Metrics) HTTPVersionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.httpRequests.WithLabelValues(logging.HTTPVersion(r)).Inc()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PanicRecovered counts a recovered panic of a handler of procedure.
+func (m *Metrics) PanicRecovered(procedure string) {
+	m.panics.WithLabelValues(procedure).Inc()
+}
+
+// Limited counts a call to procedure rejected by limit, rate or streams.
+func (m *Metrics) Limited(procedure, limit string) {
+	m.limited.WithLabelValues(procedure, limit).Inc()
+}
+
+// Interceptor returns a Connect interceptor recording the metrics of every
+// call, so handlers registered with it are measured without further changes.
+func (m *Metrics) Interceptor() connect.Interceptor {
+	return interceptor{m}
+}
+
+// interceptor records the call metrics of m.
+type interceptor struct {
+	m *Metrics
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		done := i.m.start(req.Spec())
+		resp, err := next(ctx, req)
+		done(err)
+		return resp, err
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		procedure := conn.Spec().Procedure
+		streams := i.m.streamsInFlight.WithLabelValues(procedure)
+		streams.Inc()
+		defer streams.Dec()
+
+		done := i.m.start(conn.Spec())
+		err := next(ctx, &countingConn{
+			StreamingHandlerConn: conn,
+			received:             i.m.messagesReceived.WithLabelValues(procedure),
+			sent:                 i.m.messagesSent.WithLabelValues(procedure),
+		})
+		done(err)
+		return err
+	}
+}
+
+// start counts a started call of spec and returns the function recording its
+// outcome and duration.
+func (m *Metrics) start(spec connect.Spec) func(error) {
+	begin := time.Now()
+	streamType := streamTypeLabel(spec.StreamType)
+	m.started.WithLabelValues(spec.Procedure, streamType).Inc()
+
+	return func(err error) {
+		code := "ok"
+		if err != nil {
+			code = connect.CodeOf(err).String()
+		}
+		m.handled.WithLabelValues(spec.Procedure, streamType, code).Inc()
+		m.handlingSeconds.WithLabelValues(spec.Procedure, streamType).Observe(time.Since(begin).Seconds())
+	}
+}
+
+// streamTypeLabel returns the label value of a stream type.
+func streamTypeLabel(streamType connect.StreamType) string {
+	switch streamType {
+	case connect.StreamTypeClient:
+		return "client_stream"
+	case connect.StreamTypeServer:
+		return "server_stream"
+	case connect.StreamTypeBidi:
+		return "bidi_stream"
+	default:
+		return "unary"
+	}
+}
+
+// countingConn counts the messages received and sent on a stream.
+type countingConn struct {
+	connect.StreamingHandlerConn
+	received prometheus.Counter
+	sent     prometheus.Counter
+}
+
+// Receive implements connect.StreamingHandlerConn.
+func (c *countingConn) Receive(msg any) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		c.received.Inc()
+	}
+	return err
+}
+
+// Send implements connect.StreamingHandlerConn.
+func (c *countingConn) Send(msg any) error {
+	err := c.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		c.sent.Inc()
+	}
+	return err
+}
+
+// jobsCollector reports the background operations of a StateManager by state
+// and the operations it evicted by reason.
+type jobsCollector struct {
+	states  *utils.StateManager
+	desc    *prometheus.Desc
+	evicted *prometheus.Desc
+}
+
+func newJobsCollector(states *utils.StateManager) *jobsCollector {
+	return &jobsCollector{
+		states: states,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "background", "jobs"),
+			"Background operations tracked by the service by state.",
+			[]string{"state"}, nil,
+		),
+		evicted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "background", "jobs_evicted_total"),
+			"Finished background operations evicted by reason (ttl or capacity).",
+			[]string{"reason"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *jobsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+	ch <- c.evicted
+}
+
+// Collect implements prometheus.Collector. Every state is reported, including
+// those without operations, so dashboards do not show gaps.
+func (c *jobsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := make(map[string]int)
+	for value, name := range basicServiceV1.State_name {
+		if value != int32(basicServiceV1.State_STATE_UNSPECIFIED) {
+			counts[name] = 0
+		}
+	}
+	for _, snapshot := range c.states.Snapshot() {
+		counts[snapshot.State]++
+	}
+
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), state)
+	}
+	for reason, count := range c.states.Evictions() {
+		ch <- prometheus.MustNewConstMetric(c.evicted, prometheus.CounterValue, float64(count), reason)
+	}
+}
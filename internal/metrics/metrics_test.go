@@ -0,0 +1,209 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/metrics"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// scrape returns the exposition of m.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestInterceptor(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New(utils.NewStateManager())
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Ok", connect.NewUnaryHandler(
+		"/test.v1.TestService/Ok",
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(m.Interceptor()),
+	))
+	mux.Handle("/test.v1.TestService/Fail", connect.NewUnaryHandler(
+		"/test.v1.TestService/Fail",
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("bad request"))
+		},
+		connect.WithInterceptors(m.Interceptor()),
+	))
+	mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Stream",
+		func(_ context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			for range 3 {
+				if err := stream.Send(&emptypb.Empty{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		connect.WithInterceptors(m.Interceptor()),
+	))
+	server := httptest.NewServer(m.HTTPVersionHandler(mux))
+	defer server.Close()
+
+	unary := func(procedure string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		return err
+	}
+	require.NoError(t, unary("/test.v1.TestService/Ok"))
+	require.NoError(t, unary("/test.v1.TestService/Ok"))
+	require.Error(t, unary("/test.v1.TestService/Fail"))
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Stream")
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	for stream.Receive() {
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+
+	exposition := scrape(t, m)
+
+	t.Run("should count calls by procedure and code", func(t *testing.T) {
+		assert.Contains(t, exposition, `basic_rpc_started_total{procedure="/test.v1.TestService/Ok",type="unary"} 2`)
+		assert.Contains(t, exposition, `basic_rpc_handled_total{code="ok",procedure="/test.v1.TestService/Ok",type="unary"} 2`)
+		assert.Contains(t, exposition, `basic_rpc_handled_total{code="invalid_argument",procedure="/test.v1.TestService/Fail",type="unary"} 1`)
+		assert.Contains(t, exposition, `basic_rpc_handled_total{code="ok",procedure="/test.v1.TestService/Stream",type="server_stream"} 1`)
+	})
+
+	t.Run("should measure call latency", func(t *testing.T) {
+		assert.Contains(t, exposition, `basic_rpc_handling_seconds_count{procedure="/test.v1.TestService/Ok",type="unary"} 2`)
+	})
+
+	t.Run("should count stream messages", func(t *testing.T) {
+		assert.Contains(t, exposition, `basic_rpc_stream_messages_received_total{procedure="/test.v1.TestService/Stream"} 1`)
+		assert.Contains(t, exposition, `basic_rpc_stream_messages_sent_total{procedure="/test.v1.TestService/Stream"} 3`)
+		assert.Contains(t, exposition, `basic_rpc_streams_in_flight{procedure="/test.v1.TestService/Stream"} 0`)
+	})
+
+	t.Run("should count requests by HTTP version", func(t *testing.T) {
+		assert.Contains(t, exposition, `basic_http_requests_total{http_version="http/1.1"} 4`)
+	})
+}
+
+func TestInterceptorStreamsInFlight(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New(utils.NewStateManager())
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Stream",
+		func(context.Context, *connect.Request[emptypb.Empty], *connect.ServerStream[emptypb.Empty]) error {
+			close(entered)
+			<-release
+			return nil
+		},
+		connect.WithInterceptors(m.Interceptor()),
+	))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Stream")
+	done := make(chan error, 1)
+	go func() {
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		if err == nil {
+			for stream.Receive() {
+			}
+			err = stream.Close()
+		}
+		done <- err
+	}()
+
+	t.Run("should report open streams", func(t *testing.T) {
+		<-entered
+		assert.Contains(t, scrape(t, m), `basic_rpc_streams_in_flight{procedure="/test.v1.TestService/Stream"} 1`)
+	})
+
+	close(release)
+	require.NoError(t, <-done)
+
+	t.Run("should not report closed streams", func(t *testing.T) {
+		assert.Contains(t, scrape(t, m), `basic_rpc_streams_in_flight{procedure="/test.v1.TestService/Stream"} 0`)
+	})
+}
+
+func TestJobs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should report background jobs by state", func(t *testing.T) {
+		states := utils.NewStateManager()
+		states.Start("job-1")
+		states.Start("job-2")
+		states.Start("job-3")
+		states.Finish("job-2")
+		states.SetError("job-3", errors.New("service-1 unavailable"))
+		states.Finish("job-3")
+
+		exposition := scrape(t, metrics.New(states))
+
+		assert.Contains(t, exposition, `basic_background_jobs{state="STATE_PROCESS"} 1`)
+		assert.Contains(t, exposition, `basic_background_jobs{state="STATE_COMPLETE"} 1`)
+		assert.Contains(t, exposition, `basic_background_jobs{state="STATE_COMPLETE_WITH_ERROR"} 1`)
+		assert.Contains(t, exposition, `basic_background_jobs{state="STATE_ERROR"} 0`)
+		assert.NotContains(t, exposition, "STATE_UNSPECIFIED")
+	})
+
+	t.Run("should count evicted background jobs by reason", func(t *testing.T) {
+		states := utils.NewStateManagerWithRetention(utils.Retention{MaxEntries: 1})
+		states.Start("job-1")
+		states.Finish("job-1")
+		states.Start("job-2")
+		states.Finish("job-2")
+
+		exposition := scrape(t, metrics.New(states))
+
+		assert.Contains(t, exposition, `basic_background_jobs_evicted_total{reason="capacity"} 1`)
+		assert.Contains(t, exposition, `basic_background_jobs_evicted_total{reason="ttl"} 0`)
+		assert.Contains(t, exposition, `basic_background_jobs{state="STATE_COMPLETE"} 1`)
+	})
+}
+
+func TestPanicRecovered(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should count recovered panics by procedure", func(t *testing.T) {
+		m := metrics.New(utils.NewStateManager())
+
+		m.PanicRecovered("/basic.v1.BasicService/Background")
+		m.PanicRecovered("/basic.v1.BasicService/Background")
+
+		assert.Contains(t, scrape(t, m), `basic_rpc_panics_total{procedure="/basic.v1.BasicService/Background"} 2`)
+	})
+}
+
+func TestLimited(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should count rejected calls by procedure and limit", func(t *testing.T) {
+		m := metrics.New(utils.NewStateManager())
+
+		m.Limited("/basic.v1.BasicService/Background", "streams")
+		m.Limited("/basic.v1.BasicService/Hello", "rate")
+
+		body := scrape(t, m)
+		assert.Contains(t, body, `basic_rpc_limited_total{limit="streams",procedure="/basic.v1.BasicService/Background"} 1`)
+		assert.Contains(t, body, `basic_rpc_limited_total{limit="rate",procedure="/basic.v1.BasicService/Hello"} 1`)
+	})
+}
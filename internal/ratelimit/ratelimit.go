@@ -0,0 +1,207 @@
+// Package ratelimit limits how often and how many streams at once a single
+// client may call a procedure. Rates are enforced with a token bucket per
+// client and procedure, and calls exceeding a limit fail with
+// CodeResourceExhausted and a Retry-After hint.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"golang.org/x/time/rate"
+)
+
+// Kinds of limits passed to the rejected callback.
+const (
+	LimitRate    = "rate"    // The token bucket of the client was empty
+	LimitStreams = "streams" // The client had too many streams open
+)
+
+// RetryAfterHeader carries the seconds a client should wait before retrying a
+// rejected call.
+const RetryAfterHeader = "Retry-After"
+
+// sweepInterval is how often clients without open streams and with a full
+// token bucket are forgotten, so the state does not grow with every client
+// ever seen.
+const sweepInterval = time.Minute
+
+// clientKey identifies the limits of a client for a procedure.
+type clientKey struct {
+	procedure string
+	client    string
+}
+
+// client is the limit state of a client for a procedure.
+type client struct {
+	limit   config.Limit
+	bucket  *rate.Limiter // nil without rate limit
+	streams int
+}
+
+// limiter tracks the limit state of every client.
+type limiter struct {
+	cfg      config.LimitsConfig
+	rejected func(procedure, limit string)
+
+	mu        sync.Mutex
+	clients   map[clientKey]*client
+	lastSweep time.Time
+}
+
+// NewInterceptor returns a Connect interceptor enforcing the limits of cfg on
+// unary and streaming calls. It must run after the interceptor of
+// auth.NewInterceptor to key limits by principal. rejected is called with the
+// procedure and LimitRate or LimitStreams for every rejected call, e.g. to
+// count them.
+func NewInterceptor(cfg config.LimitsConfig, rejected func(procedure, limit string)) connect.Interceptor {
+	return &limiter{cfg: cfg, rejected: rejected, clients: map[clientKey]*client{}, lastSweep: time.Now()}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (l *limiter) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		release, err := l.acquire(ctx, req.Spec(), req.Peer(), req.Header())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (*limiter) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (l *limiter) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		release, err := l.acquire(ctx, conn.Spec(), conn.Peer(), conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		defer release()
+		return next(ctx, conn)
+	}
+}
+
+// acquire takes a token from the bucket of the calling client and, for
+// streaming calls, a stream slot. The returned function releases the slot when
+// the call finished.
+func (l *limiter) acquire(ctx context.Context, spec connect.Spec, peer connect.Peer, header http.Header) (func(), error) {
+	limit := l.cfg.For(spec.Procedure)
+	if limit == (config.Limit{}) {
+		return func() {}, nil
+	}
+	streaming := spec.StreamType != connect.StreamTypeUnary && limit.MaxStreams > 0
+	key := clientKey{procedure: spec.Procedure, client: l.clientOf(ctx, peer, header)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	c := l.clients[key]
+	if c == nil {
+		c = &client{limit: limit}
+		if limit.Rate > 0 {
+			c.bucket = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		}
+		l.clients[key] = c
+	}
+
+	if streaming && c.streams >= limit.MaxStreams {
+		return nil, l.reject(ctx, spec.Procedure, key.client, LimitStreams, basicerrors.ReasonStreamLimitExceeded, time.Second,
+			fmt.Sprintf("limit of %d concurrent streams to %s exceeded", limit.MaxStreams, spec.Procedure))
+	}
+	if c.bucket != nil {
+		reservation := c.bucket.ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return nil, l.reject(ctx, spec.Procedure, key.client, LimitRate, basicerrors.ReasonRateLimited, delay,
+				fmt.Sprintf("rate limit of %g calls per second to %s exceeded", limit.Rate, spec.Procedure))
+		}
+	}
+	if !streaming {
+		return func() {}, nil
+	}
+
+	c.streams++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		c.streams--
+	}, nil
+}
+
+// reject reports a call rejected by limit and returns the error sent to the
+// client with reason, asking it to retry after at least retryAfter.
+func (l *limiter) reject(ctx context.Context, procedure, client, limit, reason string, retryAfter time.Duration, message string) error {
+	l.rejected(procedure, limit)
+	slog.DebugContext(ctx, "Call rejected by limit", "limit", limit, "client", client)
+
+	seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	err := rpcerror.New(connect.CodeResourceExhausted, reason, fmt.Errorf("%s, retry after %ss", message, seconds), "limit", limit)
+	err.Meta().Set(RetryAfterHeader, seconds)
+	return rpcerror.RetryAfter(err, retryAfter)
+}
+
+// sweep forgets clients whose state equals that of a new client. It must be
+// called with l.mu held.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, c := range l.clients {
+		if c.streams == 0 && (c.bucket == nil || c.bucket.TokensAt(now) >= float64(c.limit.Burst)) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// clientOf returns the key of the calling client as configured by cfg.Key,
+// falling back to the peer IP if the call carries no principal or credentials.
+func (l *limiter) clientOf(ctx context.Context, peer connect.Peer, header http.Header) string {
+	switch l.cfg.Key {
+	case config.LimitKeyPrincipal:
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			return "principal:" + principal.Subject
+		}
+	case config.LimitKeyAPIKey:
+		credentials := header.Get(auth.APIKeyHeader)
+		if credentials == "" {
+			credentials = header.Get("Authorization")
+		}
+		if credentials != "" {
+			// Keep a digest only so the credentials are not held in memory or logged.
+			digest := sha256.Sum256([]byte(credentials))
+			return "api_key:" + hex.EncodeToString(digest[:8])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(peer.Addr)
+	if err != nil {
+		host = peer.Addr
+	}
+	return "peer:" + host
+}
@@ -0,0 +1,179 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// rejections records the calls passed to the rejected callback.
+type rejections struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *rejections) record(procedure, limit string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, procedure+" "+limit)
+}
+
+func (r *rejections) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// newServer serves a unary and a blocking server streaming procedure behind
+// API key authentication and the limits of cfg. Streams send one message and
+// then wait until release is closed.
+func newServer(t *testing.T, cfg config.LimitsConfig, rejected *rejections, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+
+	keysFile := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(keysFile, []byte("ci-pipeline: secret-1\ndashboard: secret-2\n"), 0o600))
+	keys, err := auth.LoadAPIKeys(keysFile)
+	require.NoError(t, err)
+
+	interceptors := connect.WithInterceptors(auth.NewInterceptor(keys, nil), ratelimit.NewInterceptor(cfg, rejected.record))
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Unary", connect.NewUnaryHandler(
+		"/test.v1.TestService/Unary",
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		interceptors,
+	))
+	mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Stream",
+		func(ctx context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			if err := stream.Send(&emptypb.Empty{}); err != nil {
+				return err
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+		interceptors,
+	))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// unary calls the unary procedure on server with the given API key.
+func unary(server *httptest.Server, key string) error {
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Unary")
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set(auth.APIKeyHeader, key)
+	_, err := client.CallUnary(context.Background(), req)
+	return err
+}
+
+// openStream opens a stream on server with the given API key and waits for its
+// first message.
+func openStream(t *testing.T, server *httptest.Server, key string) (*connect.ServerStreamForClient[emptypb.Empty], error) {
+	t.Helper()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Stream")
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set(auth.APIKeyHeader, key)
+	stream, err := client.CallServerStream(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	if !stream.Receive() {
+		err := stream.Err()
+		stream.Close()
+		return nil, err
+	}
+	t.Cleanup(func() { stream.Close() })
+	return stream, nil
+}
+
+func TestNewInterceptor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should reject calls exceeding the rate with a retry hint", func(t *testing.T) {
+		var rejected rejections
+		cfg := config.Default().Limits
+		cfg.Limit = config.Limit{Rate: 0.5, Burst: 2}
+		server := newServer(t, cfg, &rejected, nil)
+
+		require.NoError(t, unary(server, "secret-1"))
+		require.NoError(t, unary(server, "secret-1"))
+		err := unary(server, "secret-1")
+
+		var connectErr *connect.Error
+		require.True(t, errors.As(err, &connectErr))
+		assert.Equal(t, connect.CodeResourceExhausted, connectErr.Code())
+		assert.Equal(t, "2", connectErr.Meta().Get(ratelimit.RetryAfterHeader))
+		assert.ErrorContains(t, err, "rate limit of 0.5 calls per second to /test.v1.TestService/Unary exceeded, retry after 2s")
+		assert.Equal(t, []string{"/test.v1.TestService/Unary rate"}, rejected.get())
+	})
+
+	t.Run("should track limits by principal", func(t *testing.T) {
+		var rejected rejections
+		cfg := config.Default().Limits
+		cfg.Limit = config.Limit{Rate: 0.01, Burst: 1}
+		server := newServer(t, cfg, &rejected, nil)
+
+		require.NoError(t, unary(server, "secret-1"))
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(unary(server, "secret-1")))
+		assert.NoError(t, unary(server, "secret-2"))
+	})
+
+	t.Run("should track limits by peer", func(t *testing.T) {
+		var rejected rejections
+		cfg := config.Default().Limits
+		cfg.Key = config.LimitKeyPeer
+		cfg.Limit = config.Limit{Rate: 0.01, Burst: 1}
+		server := newServer(t, cfg, &rejected, nil)
+
+		require.NoError(t, unary(server, "secret-1"))
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(unary(server, "secret-2")))
+	})
+
+	t.Run("should limit concurrent streams per procedure", func(t *testing.T) {
+		var rejected rejections
+		release := make(chan struct{})
+		cfg := config.Default().Limits
+		cfg.Procedures["/test.v1.TestService/Stream"] = config.Limit{MaxStreams: 2}
+		server := newServer(t, cfg, &rejected, release)
+
+		first, err := openStream(t, server, "secret-1")
+		require.NoError(t, err)
+		_, err = openStream(t, server, "secret-1")
+		require.NoError(t, err)
+
+		_, err = openStream(t, server, "secret-1")
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "limit of 2 concurrent streams to /test.v1.TestService/Stream exceeded")
+		_, err = openStream(t, server, "secret-2")
+		assert.NoError(t, err, "other principals have their own streams")
+		assert.NoError(t, unary(server, "secret-1"), "unary calls are not limited by the stream limit")
+
+		close(release)
+		for first.Receive() {
+		}
+		require.NoError(t, first.Err())
+		_, err = openStream(t, server, "secret-1")
+		assert.NoError(t, err, "finished streams free their slot")
+		assert.Equal(t, []string{"/test.v1.TestService/Stream streams"}, rejected.get())
+	})
+}
@@ -0,0 +1,127 @@
+// Package recovery keeps the server alive when a handler panics. The panic is
+// logged with its stack and the call fails with a Connect internal error that
+// carries a correlation ID to find the log record. Goroutines started by
+// handlers recover with Run and Go instead, which report the error to the
+// handler set with WithHandler.
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+)
+
+// interceptor converts panics of handlers into errors.
+type interceptor struct {
+	recovered func(procedure string)
+}
+
+// NewInterceptor returns a Connect interceptor recovering from panics of unary
+// and streaming handlers. recovered, if not nil, is called with the procedure
+// of every recovered call, e.g. to count panics. The interceptor should be the
+// last one so the others see the resulting error.
+func NewInterceptor(recovered func(procedure string)) connect.Interceptor {
+	return interceptor{recovered: recovered}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (resp connect.AnyResponse, err error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				resp, err = nil, i.handle(ctx, req.Spec(), r)
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = i.handle(ctx, conn.Spec(), r)
+			}
+		}()
+		return next(ctx, conn)
+	}
+}
+
+// handle logs the panic value r of a call and returns the error reported to
+// the client. http.ErrAbortHandler is passed on, it deliberately aborts the
+// response.
+func (i interceptor) handle(ctx context.Context, spec connect.Spec, r any) error {
+	if err, ok := r.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+		panic(r)
+	}
+
+	err := report(ctx, r)
+	if i.recovered != nil {
+		i.recovered(spec.Procedure)
+	}
+	return err
+}
+
+// handlerKey is the context key of the function Run reports panics to.
+type handlerKey struct{}
+
+// WithHandler returns a copy of ctx whose panics recovered by Run and Go are
+// passed to recovered, e.g. to fail the work the goroutines belong to.
+func WithHandler(ctx context.Context, recovered func(err error)) context.Context {
+	return context.WithValue(ctx, handlerKey{}, recovered)
+}
+
+// Run calls fn and recovers from its panic, which the interceptor does not see
+// for work running outside the handler goroutine. The panic is logged like those
+// of handlers and the resulting error passed to the handler of ctx, if any.
+func Run(ctx context.Context, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := report(ctx, r)
+			if recovered, ok := ctx.Value(handlerKey{}).(func(error)); ok {
+				recovered(err)
+			}
+		}
+	}()
+	fn()
+}
+
+// Go calls fn with Run in a new goroutine.
+func Go(ctx context.Context, fn func()) {
+	go Run(ctx, fn)
+}
+
+// report logs the panic value r and returns the error reported to clients. The
+// correlation ID is the request ID if ctx has one.
+func report(ctx context.Context, r any) error {
+	correlationID, ok := logging.RequestIDFromContext(ctx)
+	if !ok {
+		correlationID = uuid.NewString()
+	}
+	slog.ErrorContext(ctx, "Recovered from panic",
+		"panic", fmt.Sprint(r),
+		"correlation_id", correlationID,
+		"stack", string(debug.Stack()),
+	)
+
+	return rpcerror.New(connect.CodeInternal, basicerrors.ReasonPanic, fmt.Errorf("internal error, correlation ID %s", correlationID),
+		"correlation_id", correlationID)
+}
@@ -0,0 +1,185 @@
+package recovery_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// panicked records the procedures passed to the recovered callback.
+type panicked struct {
+	mu         sync.Mutex
+	procedures []string
+}
+
+func (p *panicked) record(procedure string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.procedures = append(p.procedures, procedure)
+}
+
+func (p *panicked) get() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.procedures...)
+}
+
+// lockedBuffer is a bytes.Buffer written by the server and read by the test.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNewInterceptor(t *testing.T) {
+	var logs lockedBuffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &logs))
+	defer slog.SetDefault(previous)
+
+	var recovered panicked
+	interceptor := connect.WithInterceptors(recovery.NewInterceptor(recovered.record))
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Unary", connect.NewUnaryHandler(
+		"/test.v1.TestService/Unary",
+		func(_ context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			if req.Header().Get("Panic") != "" {
+				var state *int
+				_ = *state // nil pointer dereference
+			}
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		interceptor,
+	))
+	mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Stream",
+		func(_ context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			if err := stream.Send(&emptypb.Empty{}); err != nil {
+				return err
+			}
+			panic(errors.New("stream broke"))
+		},
+		interceptor,
+	))
+	server := httptest.NewServer(logging.Handler(mux))
+	defer server.Close()
+
+	unary := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Unary")
+
+	t.Run("should convert panics of unary handlers into internal errors", func(t *testing.T) {
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set("Panic", "yes")
+		req.Header().Set(logging.RequestIDHeader, "req-unary")
+
+		_, err := unary.CallUnary(context.Background(), req)
+
+		assert.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+		assert.Contains(t, err.Error(), "correlation ID req-unary")
+		assert.NotContains(t, err.Error(), "nil pointer")
+	})
+
+	t.Run("should log the panic with its stack", func(t *testing.T) {
+		var record map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			if record["msg"] == "Recovered from panic" {
+				break
+			}
+		}
+		assert.Equal(t, "Recovered from panic", record["msg"])
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "req-unary", record["correlation_id"])
+		assert.Contains(t, record["panic"], "nil pointer dereference")
+		assert.Contains(t, record["stack"], "recovery_test.go")
+	})
+
+	t.Run("should convert panics of streaming handlers into internal errors", func(t *testing.T) {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Stream")
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.NoError(t, err)
+
+		var received int
+		for stream.Receive() {
+			received++
+		}
+
+		assert.Equal(t, 1, received)
+		assert.Equal(t, connect.CodeInternal, connect.CodeOf(stream.Err()))
+		requestID := stream.ResponseHeader().Get(logging.RequestIDHeader)
+		require.NotEmpty(t, requestID)
+		assert.Contains(t, stream.Err().Error(), "correlation ID "+requestID)
+	})
+
+	t.Run("should count recovered panics by procedure", func(t *testing.T) {
+		assert.Equal(t, []string{"/test.v1.TestService/Unary", "/test.v1.TestService/Stream"}, recovered.get())
+	})
+
+	t.Run("should keep serving after a panic", func(t *testing.T) {
+		_, err := unary.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should pass the panic as internal error to the handler of the context", func(t *testing.T) {
+		var recovered error
+		ctx := recovery.WithHandler(context.Background(), func(err error) { recovered = err })
+
+		recovery.Run(ctx, func() { panic("job broke") })
+
+		require.Error(t, recovered)
+		assert.Equal(t, connect.CodeInternal, connect.CodeOf(recovered))
+		assert.Contains(t, recovered.Error(), "internal error, correlation ID ")
+		assert.NotContains(t, recovered.Error(), "job broke")
+	})
+
+	t.Run("should recover panics of goroutines started with Go", func(t *testing.T) {
+		errs := make(chan error, 1)
+		ctx := recovery.WithHandler(context.Background(), func(err error) { errs <- err })
+
+		recovery.Go(ctx, func() { panic("job broke") })
+
+		assert.Equal(t, connect.CodeInternal, connect.CodeOf(<-errs))
+	})
+
+	t.Run("should not call the handler without a panic", func(t *testing.T) {
+		ctx := recovery.WithHandler(context.Background(), func(err error) { t.Errorf("unexpected error %v", err) })
+
+		var ran bool
+		recovery.Run(ctx, func() { ran = true })
+		assert.True(t, ran)
+	})
+
+	t.Run("should recover without a handler", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			recovery.Run(context.Background(), func() { panic("job broke") })
+		})
+	})
+}
@@ -0,0 +1,183 @@
+// Package rpcerror implements the error model of the service. Errors are
+// created with New, which records a reason and the stack, and completed by the
+// interceptor of NewInterceptor with the details every error carries. The
+// details are described for clients in package basicerrors of the SDK.
+package rpcerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// maxStackDepth bounds the frames recorded for DebugInfo.
+const maxStackDepth = 32
+
+// stackError records where an error was created, for DebugInfo.
+type stackError struct {
+	err   error
+	stack []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+
+// New returns an error with code and cause err that reports reason, one of the
+// basicerrors reasons, in its ErrorInfo. metadata are key and value pairs added
+// to the ErrorInfo.
+func New(code connect.Code, reason string, err error, metadata ...string) *connect.Error {
+	stack := make([]uintptr, maxStackDepth)
+	stack = stack[:runtime.Callers(2, stack)]
+
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: basicerrors.Domain}
+	for i := 0; i+1 < len(metadata); i += 2 {
+		if info.Metadata == nil {
+			info.Metadata = map[string]string{}
+		}
+		info.Metadata[metadata[i]] = metadata[i+1]
+	}
+
+	connectErr := connect.NewError(code, &stackError{err: err, stack: stack})
+	addDetail(connectErr, info)
+	return connectErr
+}
+
+// RetryAfter adds a RetryInfo asking the client to retry after delay to err.
+func RetryAfter(err *connect.Error, delay time.Duration) *connect.Error {
+	addDetail(err, &errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	return err
+}
+
+// BadRequest adds a BadRequest listing violations, the fields of the request
+// that are invalid, to err.
+func BadRequest(err *connect.Error, violations ...*errdetails.BadRequest_FieldViolation) *connect.Error {
+	addDetail(err, &errdetails.BadRequest{FieldViolations: violations})
+	return err
+}
+
+// addDetail adds msg as detail to err. Details are well-known messages that
+// always marshal.
+func addDetail(err *connect.Error, msg proto.Message) {
+	if detail, detailErr := connect.NewErrorDetail(msg); detailErr == nil {
+		err.AddDetail(detail)
+	}
+}
+
+// interceptor completes the details of the errors returned by handlers.
+type interceptor struct {
+	debug bool
+}
+
+// NewInterceptor returns a Connect interceptor completing the errors of unary
+// and streaming handlers, including those of the interceptors after it. Every
+// error gets an ErrorInfo, with the code as reason unless created with New, and
+// a RequestInfo with the request ID. With debug, a DebugInfo holding the cause
+// and the stack where the error was created is added as well; it reveals
+// internals and should only be enabled in development.
+func NewInterceptor(debug bool) connect.Interceptor {
+	return interceptor{debug: debug}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		resp, err := next(ctx, req)
+		if err != nil && !req.Spec().IsClient {
+			return nil, i.complete(ctx, err)
+		}
+		return resp, err
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := next(ctx, conn); err != nil {
+			return i.complete(ctx, err)
+		}
+		return nil
+	}
+}
+
+// complete adds the details err is missing.
+func (i interceptor) complete(ctx context.Context, err error) error {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		connectErr = connect.NewError(codeOf(err), err)
+	}
+
+	var hasInfo, hasRequestInfo, hasDebugInfo bool
+	for _, detail := range connectErr.Details() {
+		switch detail.Type() {
+		case "google.rpc.ErrorInfo":
+			hasInfo = true
+		case "google.rpc.RequestInfo":
+			hasRequestInfo = true
+		case "google.rpc.DebugInfo":
+			hasDebugInfo = true
+		}
+	}
+
+	if !hasInfo {
+		addDetail(connectErr, &errdetails.ErrorInfo{
+			Reason: strings.ToUpper(connectErr.Code().String()),
+			Domain: basicerrors.Domain,
+		})
+	}
+	if requestID, ok := logging.RequestIDFromContext(ctx); ok && !hasRequestInfo {
+		addDetail(connectErr, &errdetails.RequestInfo{RequestId: requestID})
+	}
+	if i.debug && !hasDebugInfo {
+		addDetail(connectErr, debugInfo(connectErr))
+	}
+	return connectErr
+}
+
+// codeOf returns the code Connect reports for err, an error that is not a
+// Connect error.
+func codeOf(err error) connect.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return connect.CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.CodeDeadlineExceeded
+	default:
+		return connect.CodeUnknown
+	}
+}
+
+// debugInfo returns the cause of err and the stack it was created at by New.
+func debugInfo(err *connect.Error) *errdetails.DebugInfo {
+	info := &errdetails.DebugInfo{}
+	if cause := err.Unwrap(); cause != nil {
+		info.Detail = cause.Error()
+	}
+
+	var stackErr *stackError
+	if errors.As(err, &stackErr) {
+		frames := runtime.CallersFrames(stackErr.stack)
+		for {
+			frame, more := frames.Next()
+			info.StackEntries = append(info.StackEntries, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+			if !more {
+				break
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,148 @@
+package rpcerror_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// newServer serves procedures failing with the errors of the handlers.
+func newServer(t *testing.T, debug bool) *httptest.Server {
+	t.Helper()
+
+	interceptor := connect.WithInterceptors(rpcerror.NewInterceptor(debug))
+	mux := http.NewServeMux()
+	unary := map[string]func() error{
+		"/test.v1.TestService/Limited": func() error {
+			err := rpcerror.New(connect.CodeResourceExhausted, basicerrors.ReasonRateLimited, errors.New("slow down"), "limit", "rate")
+			return rpcerror.RetryAfter(err, 2*time.Second)
+		},
+		"/test.v1.TestService/Plain":    func() error { return errors.New("boom") },
+		"/test.v1.TestService/Deadline": func() error { return context.DeadlineExceeded },
+	}
+	for procedure, fail := range unary {
+		mux.Handle(procedure, connect.NewUnaryHandler(
+			procedure,
+			func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				return nil, fail()
+			},
+			interceptor,
+		))
+	}
+	mux.Handle("/test.v1.TestService/Stream", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Stream",
+		func(context.Context, *connect.Request[emptypb.Empty], *connect.ServerStream[emptypb.Empty]) error {
+			return connect.NewError(connect.CodeUnavailable, errors.New("draining"))
+		},
+		interceptor,
+	))
+
+	server := httptest.NewUnstartedServer(logging.Handler(mux))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// call calls the unary procedure of server with request ID requestID and
+// returns the details of the error.
+func call(t *testing.T, server *httptest.Server, procedure, requestID string) *basicerrors.Details {
+	t.Helper()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set(logging.RequestIDHeader, requestID)
+	_, err := client.CallUnary(context.Background(), req)
+	require.Error(t, err)
+
+	details, ok := basicerrors.FromError(err)
+	require.True(t, ok)
+	return details
+}
+
+func TestNewInterceptor(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(t, false)
+
+	t.Run("should keep the reason, metadata and retry delay of errors created with New", func(t *testing.T) {
+		details := call(t, server, "/test.v1.TestService/Limited", "req-1")
+
+		assert.Equal(t, connect.CodeResourceExhausted, details.Code)
+		assert.Equal(t, "slow down", details.Message)
+		assert.Equal(t, basicerrors.ReasonRateLimited, details.Reason)
+		assert.Equal(t, basicerrors.Domain, details.Domain)
+		assert.Equal(t, map[string]string{"limit": "rate"}, details.Metadata)
+		assert.Equal(t, 2*time.Second, details.RetryDelay)
+	})
+
+	t.Run("should attach the request ID to every error", func(t *testing.T) {
+		details := call(t, server, "/test.v1.TestService/Limited", "req-2")
+
+		assert.Equal(t, "req-2", details.RequestID)
+	})
+
+	t.Run("should report the code as reason of other errors", func(t *testing.T) {
+		plain := call(t, server, "/test.v1.TestService/Plain", "req-3")
+		deadline := call(t, server, "/test.v1.TestService/Deadline", "req-4")
+
+		assert.Equal(t, connect.CodeUnknown, plain.Code)
+		assert.Equal(t, "UNKNOWN", plain.Reason)
+		assert.Equal(t, basicerrors.Domain, plain.Domain)
+		assert.Equal(t, "req-3", plain.RequestID)
+		assert.Zero(t, plain.RetryDelay)
+		assert.Equal(t, connect.CodeDeadlineExceeded, deadline.Code)
+		assert.Equal(t, "DEADLINE_EXCEEDED", deadline.Reason)
+	})
+
+	t.Run("should complete the errors of streaming handlers", func(t *testing.T) {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Stream")
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set(logging.RequestIDHeader, "req-5")
+		stream, err := client.CallServerStream(context.Background(), req)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		details, ok := basicerrors.FromError(stream.Err())
+		require.True(t, ok)
+		assert.Equal(t, "UNAVAILABLE", details.Reason)
+		assert.Equal(t, "req-5", details.RequestID)
+	})
+
+	t.Run("should not attach debug info unless enabled", func(t *testing.T) {
+		details := call(t, server, "/test.v1.TestService/Limited", "req-6")
+
+		assert.Nil(t, details.Debug)
+	})
+
+	t.Run("should attach the cause and stack as debug info if enabled", func(t *testing.T) {
+		details := call(t, newServer(t, true), "/test.v1.TestService/Limited", "req-7")
+
+		require.NotNil(t, details.Debug)
+		assert.Equal(t, "slow down", details.Debug.GetDetail())
+		require.NotEmpty(t, details.Debug.GetStackEntries())
+		assert.Contains(t, details.Debug.GetStackEntries()[0], "rpcerror_test.newServer")
+	})
+}
+
+func TestFromError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should return false for errors that are not Connect errors", func(t *testing.T) {
+		_, ok := basicerrors.FromError(errors.New("boom"))
+
+		assert.False(t, ok)
+	})
+}
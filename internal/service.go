@@ -6,14 +6,28 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/certs"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/health"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/recovery"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
 	"github.com/soundphilosopher/basic-grpc-service-go/internal/talk"
 	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
 	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -21,27 +35,191 @@ import (
 // Hello, Talk, and Background operations with state management capabilities.
 type BasicServiceV1 struct {
 	StateManager *utils.StateManager
+	Config       config.BackgroundConfig
+	Health       *health.Checker // Optional, degraded while Background is saturated or a downstream service fails
+
+	// CallService calls a downstream service of a Background job, utils.CallService if nil.
+	CallService func(ctx context.Context, serviceName, serviceType string) chan *basicServiceV1.SomeServiceResponse
+	// PanicRecovered is called with the procedure for every recovered panic of a
+	// Background job, e.g. to count them. Optional.
+	PanicRecovered func(procedure string)
+
+	mu           sync.Mutex
+	running      int                    // Background jobs still calling downstream services
+	runningBy    map[string]int         // Running Background jobs by owner
+	jobs         map[string]*job        // Background jobs by ID, for clients reattaching to them
+	dependencies map[string]*dependency // Latest calls by downstream service name
 }
 
 // NewBasicServiceV1 creates a new BasicServiceV1 instance with an initialized StateManager.
-// The StateManager tracks the lifecycle of background operations.
-func NewBasicServiceV1() *BasicServiceV1 {
-	return &BasicServiceV1{
-		StateManager: utils.NewStateManager(),
+// The StateManager tracks the lifecycle of background operations and keeps finished ones
+// as long as cfg retains them, cfg also controls how Background reports progress.
+func NewBasicServiceV1(cfg config.BackgroundConfig) *BasicServiceV1 {
+	s := &BasicServiceV1{
+		Config:       cfg,
+		runningBy:    map[string]int{},
+		jobs:         map[string]*job{},
+		dependencies: map[string]*dependency{},
+	}
+	s.StateManager = utils.NewStateManagerWithRetention(utils.Retention{
+		TTL:        cfg.Retention,
+		MaxEntries: cfg.MaxFinishedJobs,
+		OnEvict:    s.forget,
+	})
+	return s
+}
+
+// identity returns the authenticated principal of the current call, else the
+// subject of the verified client certificate, or false without either.
+func identity(ctx context.Context) (string, bool) {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Subject, true
+	}
+	if identity, ok := certs.ClientIdentityFromContext(ctx); ok {
+		return identity.String(), true
+	}
+	return "", false
+}
+
+// caller describes the client of the current call for audit logs, its identity
+// or "anonymous" without one.
+func caller(ctx context.Context) string {
+	if id, ok := identity(ctx); ok {
+		return id
+	}
+	return "anonymous"
+}
+
+// ownerOf returns the key of the client of the current call owning the
+// Background jobs it starts: its identity, else its peer IP like the rate
+// limits, so clients without credentials neither share jobs nor limits.
+func ownerOf(ctx context.Context, peer connect.Peer) string {
+	if id, ok := identity(ctx); ok {
+		return id
+	}
+	host, _, err := net.SplitHostPort(peer.Addr)
+	if err != nil {
+		host = peer.Addr
+	}
+	return "peer:" + host
+}
+
+// admit counts a new Background job of owner as running, or rejects it with
+// ResourceExhausted if the configured limits of running jobs are reached. Jobs
+// outlive the calls that started them, so the stream limits of the call do not
+// bound them.
+func (s *BasicServiceV1) admit(owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit := s.Config.MaxRunningJobs; limit > 0 && s.running >= limit {
+		return rpcerror.New(connect.CodeResourceExhausted, basicerrors.ReasonJobLimitExceeded,
+			fmt.Errorf("limit of %d running Background jobs reached", limit), "limit", "server")
+	}
+	if limit := s.Config.MaxRunningPerCaller; limit > 0 && s.runningBy[owner] >= limit {
+		return rpcerror.New(connect.CodeResourceExhausted, basicerrors.ReasonJobLimitExceeded,
+			fmt.Errorf("limit of %d running Background jobs per caller reached", limit), "limit", "caller")
+	}
+
+	s.running++
+	s.runningBy[owner]++
+	s.updateSaturation()
+	return nil
+}
+
+// release stops counting a Background job of owner as running.
+func (s *BasicServiceV1) release(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	if s.runningBy[owner]--; s.runningBy[owner] == 0 {
+		delete(s.runningBy, owner)
+	}
+	s.updateSaturation()
+}
+
+// updateSaturation degrades the service health while the configured saturation
+// threshold of running jobs is reached, so load balancers route new work to
+// other instances. It must be called with s.mu held.
+func (s *BasicServiceV1) updateSaturation() {
+	threshold := s.Config.SaturationThreshold
+	if s.Health == nil || threshold == 0 {
+		return
+	}
+
+	if s.running >= threshold {
+		s.Health.Degrade(basicV1connect.BasicServiceName, "background", fmt.Sprintf("%d of %d jobs running", s.running, threshold))
+	} else {
+		s.Health.Recover(basicV1connect.BasicServiceName, "background")
+	}
+}
+
+// dependency holds the outcomes of the latest calls to a downstream service.
+type dependency struct {
+	failed   []bool // Ring buffer of the outcomes, true for failed calls
+	next     int    // Index of failed overwritten by the next call
+	calls    int
+	failures int
+}
+
+// record adds the outcome of a call, replacing the oldest one once the ring
+// buffer is full.
+func (d *dependency) record(failed bool) {
+	if d.calls == len(d.failed) {
+		if d.failed[d.next] {
+			d.failures--
+		}
+	} else {
+		d.calls++
+	}
+	d.failed[d.next] = failed
+	if failed {
+		d.failures++
+	}
+	d.next = (d.next + 1) % len(d.failed)
+}
+
+// recordCall records the outcome of a call to the downstream service name and
+// degrades the service health while the configured error rate is reached over
+// a full window of calls, so load balancers route new work to other instances.
+func (s *BasicServiceV1) recordCall(name string, failed bool) {
+	rate := s.Config.DependencyErrorRate
+	if s.Health == nil || rate == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dependencies[name]
+	if !ok {
+		d = &dependency{failed: make([]bool, s.Config.DependencyWindow)}
+		s.dependencies[name] = d
+	}
+	d.record(failed)
+
+	component := "dependency " + name
+	if d.calls == len(d.failed) && float64(d.failures) >= rate*float64(d.calls) {
+		s.Health.Degrade(basicV1connect.BasicServiceName, component, fmt.Sprintf("%d of the last %d calls failed", d.failures, d.calls))
+	} else {
+		s.Health.Recover(basicV1connect.BasicServiceName, component)
 	}
 }
 
 // Hello handles simple greeting requests and returns a Cloud Event response.
 // The greeting message is formatted with the provided input message.
 func (s *BasicServiceV1) Hello(ctx context.Context, req *connect.Request[basicServiceV1.HelloRequest]) (*connect.Response[basicServiceV1.HelloResponse], error) {
+	slog.InfoContext(ctx, "Hello called", "caller", caller(ctx))
+
 	event, err := anypb.New(&basicServiceV1.HelloResponseEvent{Greeting: fmt.Sprintf("Hello, %s", req.Msg.Message)})
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, rpcerror.New(connect.CodeInternal, basicerrors.ReasonEncodingFailed, err)
 	}
 
-	cloudevent, err := utils.CreateCloudEvent(req, event)
+	cloudevent, err := utils.CreateCloudEvent(ctx, req, event)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, rpcerror.New(connect.CodeInternal, basicerrors.ReasonEncodingFailed, err)
 	}
 
 	resp := connect.NewResponse(&basicServiceV1.HelloResponse{CloudEvent: cloudevent})
@@ -55,7 +233,7 @@ func (s *BasicServiceV1) Hello(ctx context.Context, req *connect.Request[basicSe
 func (s *BasicServiceV1) Talk(ctx context.Context, stream *connect.BidiStream[basicServiceV1.TalkRequest, basicServiceV1.TalkResponse]) error {
 	for {
 		if err := ctx.Err(); err != nil {
-			return connect.NewError(connect.CodeAborted, err)
+			return rpcerror.New(connect.CodeAborted, basicerrors.ReasonCanceled, err)
 		}
 
 		receive, err := stream.Receive()
@@ -63,12 +241,16 @@ func (s *BasicServiceV1) Talk(ctx context.Context, stream *connect.BidiStream[ba
 			if errors.Is(err, io.EOF) {
 				return nil
 			}
-			return connect.NewError(connect.CodeCanceled, err)
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				return connectErr // e.g. InvalidArgument for messages failing validation
+			}
+			return rpcerror.New(connect.CodeCanceled, basicerrors.ReasonStreamClosed, err)
 		}
 
 		reply, end := talk.Reply(receive.Message)
 		if err := stream.Send(&basicServiceV1.TalkResponse{Answer: reply}); err != nil {
-			return connect.NewError(connect.CodeCanceled, err)
+			return rpcerror.New(connect.CodeCanceled, basicerrors.ReasonStreamClosed, err)
 		}
 		if end {
 			return nil
@@ -77,79 +259,183 @@ func (s *BasicServiceV1) Talk(ctx context.Context, stream *connect.BidiStream[ba
 }
 
 // Background handles long-running operations by orchestrating multiple service calls
-// and streaming periodic status updates. Uses fan-out/fan-in pattern to call
-// multiple services concurrently and reports progress every 2 seconds.
+// and streaming status updates. Uses fan-out/fan-in pattern to make the requested
+// number of downstream calls, drawn from the configured service registry, concurrently
+// and reports progress, including the result of every process, as soon as a response
+// arrives or the state changes. Requests for more processes than configured are rejected,
+// as are new jobs while the configured number of jobs is running.
+//
+// The job runs independently of the call, so a client losing its connection can
+// reattach by sending the job ID of the first update as BackgroundRequest.job_id.
 func (s *BasicServiceV1) Background(ctx context.Context, req *connect.Request[basicServiceV1.BackgroundRequest], stream *connect.ServerStream[basicServiceV1.BackgroundResponse]) error {
+	if req.Msg.JobId != "" {
+		j, err := s.ownedJob(ctx, req.Peer(), req.Msg.JobId)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "Background reattached", "hash", j.id, "caller", j.owner)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("basic.hash", j.id))
+		return s.follow(ctx, req, stream, j)
+	}
+
+	if processes, limit := req.Msg.Processes, s.Config.MaxProcesses; processes > int64(limit) {
+		err := rpcerror.New(connect.CodeInvalidArgument, basicerrors.ReasonInvalidRequest, fmt.Errorf("invalid BackgroundRequest: processes: must be at most %d, got %d", limit, processes))
+		return rpcerror.BadRequest(err, &errdetails.BadRequest_FieldViolation{
+			Field:       "processes",
+			Description: fmt.Sprintf("value must be at most %d", limit),
+			Reason:      "background.max_processes",
+		})
+	}
+	targets := s.Config.Targets(int(req.Msg.Processes))
+
+	owner := ownerOf(ctx, req.Peer())
+	if err := s.admit(owner); err != nil {
+		slog.WarnContext(ctx, "Background rejected", "caller", owner, "error", err)
+		return err
+	}
+
 	hash := uuid.NewString()
-	state, _, _ := s.StateManager.GetState(hash)
-
-	data := []*basicServiceV1.SomeServiceResponse{}
-
-	// Start background processing if not already running
-	if state == nil {
-		s.StateManager.Start(hash)
-		go func() {
-			// Fan-out: call multiple services concurrently
-			s1 := utils.CallService("service-1", "rest")
-			s2 := utils.CallService("service-2", "rpc")
-			s3 := utils.CallService("service-3", "grpc")
-			s4 := utils.CallService("service-4", "rest")
-			s5 := utils.CallService("service-5", "grpc")
-
-			// Fan-in: collect responses as they arrive
-			for response := range utils.MergeServiceResponses(s1, s2, s3, s4, s5) {
-				log.Printf("Received response: %v", response)
-				data = append(data, response.Responses...)
+	slog.InfoContext(ctx, "Background started", "hash", hash, "caller", owner, "processes", len(targets))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("basic.hash", hash), attribute.Int("basic.processes", len(targets)))
+
+	// Keep the values of the call, e.g. its span and log attributes, but not
+	// its cancellation: the job outlives the call if the client disconnects and
+	// only ends early through CancelBackgroundJob.
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j := newJob(hash, owner, targets, cancel)
+	s.mu.Lock()
+	s.jobs[hash] = j
+	s.mu.Unlock()
+	s.StateManager.Start(hash)
+	go func() {
+		defer close(j.done) // Last, so cancelled jobs no longer count as running
+		defer s.release(owner)
+		defer cancel()
+
+		// Panics of the job and its downstream calls fail the job instead of
+		// the process, the recovery interceptor only covers the handler.
+		jobCtx := recovery.WithHandler(jobCtx, func(err error) {
+			s.StateManager.Fail(hash, err)
+			cancel()
+			if s.PanicRecovered != nil {
+				s.PanicRecovered(basicV1connect.BasicServiceBackgroundProcedure)
 			}
+		})
+		recovery.Run(jobCtx, func() { s.run(jobCtx, j) })
+	}()
+
+	return s.follow(ctx, req, stream, j)
+}
 
-			s.StateManager.Finish(hash)
-		}()
+// run calls the targets of j concurrently and records the result of every
+// process and the final state of j in the StateManager.
+func (s *BasicServiceV1) run(ctx context.Context, j *job) {
+	call := s.CallService
+	if call == nil {
+		call = utils.CallService
 	}
 
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
+	// Fan-out: call the target of every process concurrently
+	calls := make([]chan *basicServiceV1.SomeServiceResponse, len(j.targets))
+	for i, target := range j.targets {
+		calls[i] = call(ctx, target.Name, target.Type)
+	}
+	merged := utils.MergeServiceResponses(ctx, calls...)
+	defer func() {
+		// Stop the calls and unblock the fan-in if the job ends early, e.g. by a panic
+		j.cancel()
+		for range merged {
+		}
+	}()
 
-	// Stream status updates until processing completes
+	// Fan-in: collect responses as they arrive
+	var cancelled bool
+	for response := range merged {
+		slog.InfoContext(ctx, "Received response", "hash", j.id, "process", response.Process, "responses", response.Responses)
+		target := j.targets[response.Process]
+		result := &basicServiceV1.ProcessResult{
+			Process:   int64(response.Process),
+			Service:   target.Name,
+			Type:      target.Type,
+			Responses: response.Responses,
+		}
+		switch {
+		case len(response.Responses) > 0:
+			result.State = basicServiceV1.State_STATE_COMPLETE
+			s.recordCall(target.Name, false)
+		case ctx.Err() != nil:
+			result.State = basicServiceV1.State_STATE_CANCELLED
+			cancelled = true
+		default:
+			result.State = basicServiceV1.State_STATE_ERROR
+			s.StateManager.SetError(j.id, fmt.Errorf("process %d: %s returned no response", response.Process, target.Name))
+			s.recordCall(target.Name, true)
+		}
+		s.StateManager.AppendResult(j.id, result)
+	}
+
+	// A job cancelled after its last call returned still completed
+	if cancelled {
+		s.StateManager.Cancel(j.id)
+		return
+	}
+	s.StateManager.Finish(j.id)
+}
+
+// follow streams the current state of j right away, then on every change and,
+// while nothing changes, every heartbeat interval until j is complete or the
+// client disconnects. The request may ask for its own heartbeat interval.
+func (s *BasicServiceV1) follow(ctx context.Context, req *connect.Request[basicServiceV1.BackgroundRequest], stream *connect.ServerStream[basicServiceV1.BackgroundResponse], j *job) error {
+	changes, stop := s.StateManager.Watch(j.id)
+	defer stop()
+
+	interval := s.Config.ProgressInterval
+	if req.Msg.HeartbeatInterval != nil {
+		interval = req.Msg.HeartbeatInterval.AsDuration()
+	}
+
+	var heartbeat <-chan time.Time // nil without heartbeats or until the first update
+	var sent *basicServiceV1.BackgroundResponseEvent
 	for {
+		beat := false
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			current_state, start, finish := s.StateManager.GetState(hash)
-
-			// Send final response when processing is complete
-			if *current_state != basicServiceV1.State_STATE_PROCESS {
-				event, err := anypb.New(&basicServiceV1.BackgroundResponseEvent{State: *current_state, StartedAt: start, CompletedAt: finish, Responses: data})
-				if err != nil {
-					return connect.NewError(connect.CodeInternal, err)
-				}
+		case <-changes:
+		case <-heartbeat:
+			beat = true
+		}
 
-				cloudevent, err := utils.CreateCloudEvent(req, event)
-				if err != nil {
-					return connect.NewError(connect.CodeInternal, err)
-				}
+		progress, ok := s.event(j)
+		if !ok {
+			return errJobNotFound(j.id) // Evicted after it finished
+		}
+		if !beat && proto.Equal(progress, sent) {
+			continue // Already sent when reading an earlier change
+		}
 
-				if err := stream.Send(&basicServiceV1.BackgroundResponse{CloudEvent: cloudevent}); err != nil {
-					return connect.NewError(connect.CodeCanceled, err)
-				}
+		event, err := anypb.New(progress)
+		if err != nil {
+			return rpcerror.New(connect.CodeInternal, basicerrors.ReasonEncodingFailed, err)
+		}
 
-				return nil
-			}
+		cloudevent, err := utils.CreateCloudEvent(ctx, req, event)
+		if err != nil {
+			return rpcerror.New(connect.CodeInternal, basicerrors.ReasonEncodingFailed, err)
+		}
 
-			// Send progress update
-			event, err := anypb.New(&basicServiceV1.BackgroundResponseEvent{State: *current_state, StartedAt: start, CompletedAt: finish, Responses: data})
-			if err != nil {
-				return connect.NewError(connect.CodeInternal, err)
-			}
+		if err := stream.Send(&basicServiceV1.BackgroundResponse{CloudEvent: cloudevent}); err != nil {
+			return rpcerror.New(connect.CodeCanceled, basicerrors.ReasonStreamClosed, err)
+		}
 
-			cloudevent, err := utils.CreateCloudEvent(req, event)
-			if err != nil {
-				return connect.NewError(connect.CodeInternal, err)
-			}
+		// The final response was sent once processing is complete
+		if progress.State != basicServiceV1.State_STATE_PROCESS {
+			return nil
+		}
 
-			if err := stream.Send(&basicServiceV1.BackgroundResponse{CloudEvent: cloudevent}); err != nil {
-				return connect.NewError(connect.CodeCanceled, err)
-			}
+		sent = progress
+		if interval > 0 {
+			heartbeat = time.After(interval)
 		}
 	}
 }
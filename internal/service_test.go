@@ -0,0 +1,459 @@
+package internal_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/health"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/recovery"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newClient serves a BasicServiceV1 with cfg and returns a client calling it.
+func newClient(t *testing.T, cfg config.BackgroundConfig) basicV1connect.BasicServiceClient {
+	t.Helper()
+
+	return serve(t, internal.NewBasicServiceV1(cfg))
+}
+
+// serve serves service and returns a client calling it.
+func serve(t *testing.T, service *internal.BasicServiceV1) basicV1connect.BasicServiceClient {
+	t.Helper()
+
+	return serveClients(t, service, "127.0.0.1")[0]
+}
+
+// serveClients serves service and returns a client calling it from each of the
+// loopback addresses ips, so their calls come from different peers.
+func serveClients(t *testing.T, service *internal.BasicServiceV1, ips ...string) []basicV1connect.BasicServiceClient {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(basicV1connect.NewBasicServiceHandler(service))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	clients := make([]basicV1connect.BasicServiceClient, len(ips))
+	for i, ip := range ips {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(ip)}}).DialContext
+		clients[i] = basicV1connect.NewBasicServiceClient(&http.Client{Transport: transport}, server.URL)
+	}
+	return clients
+}
+
+// firstEvent starts a Background call for req and returns the event of its
+// first update.
+func firstEvent(t *testing.T, client basicV1connect.BasicServiceClient, req *basicServiceV1.BackgroundRequest) *basicServiceV1.BackgroundResponseEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Background(ctx, connect.NewRequest(req))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	require.True(t, stream.Receive(), stream.Err())
+	event := &basicServiceV1.BackgroundResponseEvent{}
+	require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event))
+	return event
+}
+
+func TestBackground(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default().Background
+	cfg.ProgressInterval = 10 * time.Millisecond
+	cfg.MaxProcesses = 3
+	cfg.Services = []config.ServiceConfig{{Name: "inventory", Type: "grpc"}, {Name: "billing", Type: "rest"}}
+	client := newClient(t, cfg)
+
+	t.Run("should report a result per requested process", func(t *testing.T) {
+		event := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 3})
+
+		results := event.GetResults()
+		require.Len(t, results, 3)
+		for i, want := range []config.ServiceConfig{cfg.Services[0], cfg.Services[1], cfg.Services[0]} {
+			assert.Equal(t, int64(i), results[i].GetProcess())
+			assert.Equal(t, want.Name, results[i].GetService())
+			assert.Equal(t, want.Type, results[i].GetType())
+		}
+	})
+
+	t.Run("should reject more processes than configured", func(t *testing.T) {
+		stream, err := client.Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: 4}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		details, ok := basicerrors.FromError(stream.Err())
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeInvalidArgument, details.Code)
+		assert.Equal(t, basicerrors.ReasonInvalidRequest, details.Reason)
+		require.Len(t, details.FieldViolations, 1)
+		assert.Equal(t, "processes", details.FieldViolations[0].GetField())
+	})
+
+	t.Run("should return the job ID in the first update", func(t *testing.T) {
+		event := firstEvent(t, client, &basicServiceV1.BackgroundRequest{})
+
+		assert.NoError(t, uuid.Validate(event.GetJobId()))
+		assert.Equal(t, basicServiceV1.State_STATE_PROCESS, event.GetState())
+	})
+
+	t.Run("should reattach to a job after the client disconnected", func(t *testing.T) {
+		started := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 3})
+
+		reattached := firstEvent(t, client, &basicServiceV1.BackgroundRequest{JobId: started.GetJobId(), Processes: 1})
+
+		assert.Equal(t, started.GetJobId(), reattached.GetJobId())
+		assert.True(t, started.GetStartedAt().AsTime().Equal(reattached.GetStartedAt().AsTime()))
+		assert.Len(t, reattached.GetResults(), 3)
+	})
+
+	t.Run("should report unknown jobs as not found", func(t *testing.T) {
+		stream, err := client.Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{JobId: uuid.NewString()}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		details, ok := basicerrors.FromError(stream.Err())
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeNotFound, details.Code)
+		assert.Equal(t, basicerrors.ReasonJobNotFound, details.Reason)
+	})
+
+	t.Run("should stream consistent partial results to concurrent calls", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: 3}))
+				if !assert.NoError(t, err) {
+					return
+				}
+				defer stream.Close()
+
+				for i := 0; i < 5 && stream.Receive(); i++ {
+					event := &basicServiceV1.BackgroundResponseEvent{}
+					if !assert.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event)) {
+						return
+					}
+
+					var responses int
+					for _, result := range event.GetResults() {
+						responses += len(result.GetResponses())
+						if result.GetState() == basicServiceV1.State_STATE_PROCESS {
+							assert.Empty(t, result.GetResponses())
+						}
+					}
+					assert.Len(t, event.GetResponses(), responses)
+
+					_, err := client.GetBackgroundJob(ctx, connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: event.GetJobId()}))
+					assert.NoError(t, err)
+					_, err = client.ListBackgroundJobs(ctx, connect.NewRequest(&basicServiceV1.ListBackgroundJobsRequest{}))
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("should send an update on every change without heartbeats", func(t *testing.T) {
+		slow := config.Default().Background
+		slow.ProgressInterval = time.Hour
+		client := newClient(t, slow)
+
+		stream, err := client.Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{
+			Processes:         10,
+			HeartbeatInterval: durationpb.New(0),
+		}))
+		require.NoError(t, err)
+		defer stream.Close()
+		require.True(t, stream.Receive(), stream.Err())
+		event := &basicServiceV1.BackgroundResponseEvent{}
+		require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event))
+
+		_, err = client.CancelBackgroundJob(context.Background(), connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: event.GetJobId()}))
+		require.NoError(t, err)
+
+		for stream.Receive() {
+			previous := event
+			event = &basicServiceV1.BackgroundResponseEvent{}
+			require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event))
+			assert.False(t, proto.Equal(previous, event), "unchanged progress sent again")
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, basicServiceV1.State_STATE_CANCELLED, event.GetState())
+	})
+
+	t.Run("should repeat unchanged progress at the requested heartbeat interval", func(t *testing.T) {
+		slow := config.Default().Background
+		slow.ProgressInterval = time.Hour
+		service := internal.NewBasicServiceV1(slow)
+		// The downstream call never answers, so only heartbeats send updates.
+		service.CallService = func(ctx context.Context, _, _ string) chan *basicServiceV1.SomeServiceResponse {
+			response := make(chan *basicServiceV1.SomeServiceResponse)
+			go func() {
+				defer close(response)
+				<-ctx.Done()
+			}()
+			return response
+		}
+		client := serve(t, service)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{
+			Processes:         1,
+			HeartbeatInterval: durationpb.New(100 * time.Millisecond),
+		}))
+		require.NoError(t, err)
+		defer stream.Close()
+		require.True(t, stream.Receive(), stream.Err())
+		first := &basicServiceV1.BackgroundResponseEvent{}
+		require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(first))
+		defer client.CancelBackgroundJob(context.Background(), connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: first.GetJobId()}))
+
+		received := time.Now()
+		for range 3 {
+			require.True(t, stream.Receive(), stream.Err())
+			gap := time.Since(received)
+			received = time.Now()
+			event := &basicServiceV1.BackgroundResponseEvent{}
+			require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event))
+
+			assert.True(t, proto.Equal(first, event), "progress changed")
+			assert.GreaterOrEqual(t, gap, 50*time.Millisecond)
+			assert.Less(t, gap, 500*time.Millisecond)
+		}
+	})
+}
+
+func TestBackgroundJobLimits(t *testing.T) {
+	t.Parallel()
+
+	// rejected starts a Background call and returns the details of the error it
+	// fails with.
+	rejected := func(t *testing.T, client basicV1connect.BasicServiceClient) *basicerrors.Details {
+		t.Helper()
+
+		stream, err := client.Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		require.False(t, stream.Receive())
+		details, ok := basicerrors.FromError(stream.Err())
+		require.True(t, ok)
+		return details
+	}
+
+	for _, tc := range []struct {
+		name  string
+		limit string
+		cfg   func(*config.BackgroundConfig)
+	}{
+		{name: "should limit running jobs per caller", limit: "caller", cfg: func(cfg *config.BackgroundConfig) { cfg.MaxRunningPerCaller = 1 }},
+		{name: "should limit running jobs of the server", limit: "server", cfg: func(cfg *config.BackgroundConfig) { cfg.MaxRunningJobs = 1 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default().Background
+			tc.cfg(&cfg)
+			client := newClient(t, cfg)
+
+			// The job keeps running after the client disconnected.
+			running := firstEvent(t, client, &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+
+			details := rejected(t, client)
+			assert.Equal(t, connect.CodeResourceExhausted, details.Code)
+			assert.Equal(t, basicerrors.ReasonJobLimitExceeded, details.Reason)
+			assert.Equal(t, tc.limit, details.Metadata["limit"])
+
+			_, err := client.CancelBackgroundJob(context.Background(), connect.NewRequest(&basicServiceV1.CancelBackgroundJobRequest{JobId: running}))
+			require.NoError(t, err)
+			firstEvent(t, client, &basicServiceV1.BackgroundRequest{}) // Cancelled jobs no longer count
+		})
+	}
+}
+
+func TestBackgroundAnonymousCallers(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default().Background
+	cfg.MaxRunningPerCaller = 1
+	clients := serveClients(t, internal.NewBasicServiceV1(cfg), "127.0.0.1", "127.0.0.2")
+	first := firstEvent(t, clients[0], &basicServiceV1.BackgroundRequest{Processes: 10}).GetJobId()
+
+	t.Run("should limit callers without credentials by their peer", func(t *testing.T) {
+		firstEvent(t, clients[1], &basicServiceV1.BackgroundRequest{Processes: 10})
+
+		stream, err := clients[0].Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		require.False(t, stream.Receive())
+		details, ok := basicerrors.FromError(stream.Err())
+		require.True(t, ok)
+		assert.Equal(t, connect.CodeResourceExhausted, details.Code)
+		assert.Equal(t, "caller", details.Metadata["limit"])
+	})
+
+	t.Run("should not reattach to jobs of other peers", func(t *testing.T) {
+		stream, err := clients[1].Background(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{JobId: first}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(stream.Err()))
+	})
+
+	t.Run("should reattach to jobs of the same peer", func(t *testing.T) {
+		event := firstEvent(t, clients[0], &basicServiceV1.BackgroundRequest{JobId: first})
+
+		assert.Equal(t, first, event.GetJobId())
+	})
+}
+
+func TestBackgroundDependencyHealth(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default().Background
+	cfg.Services = []config.ServiceConfig{{Name: "inventory", Type: "grpc"}}
+	cfg.DependencyErrorRate = 0.5
+	cfg.DependencyWindow = 4
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
+	checker.Ready()
+	service := internal.NewBasicServiceV1(cfg)
+	service.Health = checker
+	var failing atomic.Bool
+	service.CallService = func(_ context.Context, serviceName, _ string) chan *basicServiceV1.SomeServiceResponse {
+		response := make(chan *basicServiceV1.SomeServiceResponse, 1)
+		if !failing.Load() {
+			response <- &basicServiceV1.SomeServiceResponse{Id: uuid.NewString(), Name: serviceName}
+		}
+		close(response)
+		return response
+	}
+	client := serve(t, service)
+
+	// run follows a new Background job of processes calls to its end.
+	run := func(t *testing.T, processes int64) {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: processes}))
+		require.NoError(t, err)
+		defer stream.Close()
+		for stream.Receive() {
+		}
+		require.NoError(t, stream.Err())
+	}
+
+	t.Run("should serve while the error rate is below the threshold", func(t *testing.T) {
+		run(t, 3)
+		failing.Store(true)
+		run(t, 1)
+
+		assert.Empty(t, checker.Reasons(basicV1connect.BasicServiceName))
+	})
+
+	t.Run("should not serve once the error rate reaches the threshold", func(t *testing.T) {
+		run(t, 1)
+
+		assert.Equal(t, []string{"dependency inventory: 2 of the last 4 calls failed"}, checker.Reasons(basicV1connect.BasicServiceName))
+	})
+
+	t.Run("should serve again once calls succeed", func(t *testing.T) {
+		failing.Store(false)
+		run(t, 3)
+
+		assert.Empty(t, checker.Reasons(basicV1connect.BasicServiceName))
+	})
+}
+
+func TestBackgroundPanics(t *testing.T) {
+	t.Parallel()
+
+	// lastEvent follows a new Background job of client to its end and returns
+	// the event of the last update.
+	lastEvent := func(t *testing.T, client basicV1connect.BasicServiceClient) *basicServiceV1.BackgroundResponseEvent {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Background(ctx, connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: 2}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		event := &basicServiceV1.BackgroundResponseEvent{}
+		for stream.Receive() {
+			require.NoError(t, stream.Msg().GetCloudEvent().GetProtoData().UnmarshalTo(event))
+		}
+		require.NoError(t, stream.Err())
+		return event
+	}
+
+	for _, tc := range []struct {
+		name string
+		call func(ctx context.Context, serviceName, serviceType string) chan *basicServiceV1.SomeServiceResponse
+	}{
+		{name: "should fail the job when the worker panics", call: func(context.Context, string, string) chan *basicServiceV1.SomeServiceResponse {
+			panic("downstream broke")
+		}},
+		{name: "should fail the job when a downstream call panics", call: func(ctx context.Context, _, _ string) chan *basicServiceV1.SomeServiceResponse {
+			response := make(chan *basicServiceV1.SomeServiceResponse)
+			recovery.Go(ctx, func() {
+				defer close(response)
+				panic("downstream broke")
+			})
+			return response
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var recovered atomic.Int32
+			service := internal.NewBasicServiceV1(config.Default().Background)
+			service.CallService = tc.call
+			service.PanicRecovered = func(procedure string) {
+				assert.Equal(t, basicV1connect.BasicServiceBackgroundProcedure, procedure)
+				recovered.Add(1)
+			}
+			client := serve(t, service)
+
+			event := lastEvent(t, client)
+
+			assert.Equal(t, basicServiceV1.State_STATE_ERROR, event.GetState())
+			assert.NotNil(t, event.GetCompletedAt())
+			for _, result := range event.GetResults() {
+				assert.NotEqual(t, basicServiceV1.State_STATE_PROCESS, result.GetState())
+			}
+			resp, err := client.GetBackgroundJob(context.Background(), connect.NewRequest(&basicServiceV1.GetBackgroundJobRequest{JobId: event.GetJobId()}))
+			require.NoError(t, err)
+			assert.Contains(t, strings.Join(resp.Msg.GetJob().GetErrors(), "\n"), "internal error, correlation ID")
+			assert.Positive(t, recovered.Load())
+		})
+	}
+}
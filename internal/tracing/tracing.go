@@ -0,0 +1,80 @@
+// Package tracing sets up the OpenTelemetry traces of the service. Spans are
+// exported to an OTLP/HTTP collector and the W3C trace context is propagated
+// through the traceparent and tracestate headers.
+package tracing
+
+import (
+	"context"
+	"net/url"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/otelconnect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies the service in exported traces unless overridden by
+// OTEL_SERVICE_NAME.
+const ServiceName = "basic-grpc-service"
+
+// tracesPath is appended to the collector URL, as for OTEL_EXPORTER_OTLP_ENDPOINT.
+const tracesPath = "v1/traces"
+
+// Setup installs the W3C trace context propagator and, if cfg.Endpoint is set,
+// a global tracer provider exporting spans to it in batches. Without endpoint
+// no spans are recorded, but incoming trace contexts are still passed on. The
+// returned function flushes pending spans and must be called before exiting.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint, err := url.JoinPath(cfg.Endpoint, tracesPath)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(cfg, sdktrace.NewBatchSpanProcessor(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider passing spans to processor. It records
+// cfg.SampleRatio of new traces and every span whose parent was sampled, so the
+// decision of upstream services is kept. Tests pass a processor wrapping an
+// in-memory exporter.
+func NewProvider(cfg config.TracingConfig, processor sdktrace.SpanProcessor) *sdktrace.TracerProvider {
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		// Only invalid OTEL_RESOURCE_ATTRIBUTES fail, keep what could be detected.
+		otel.Handle(err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithSpanProcessor(processor),
+	)
+}
+
+// NewInterceptor returns a Connect interceptor starting a server span for every
+// call with the global tracer provider. The trace context of incoming
+// traceparent headers is trusted, so the spans continue the caller's trace.
+// Metrics are recorded by the metrics package instead.
+func NewInterceptor() (connect.Interceptor, error) {
+	return otelconnect.NewInterceptor(otelconnect.WithTrustRemote(), otelconnect.WithoutMetrics())
+}
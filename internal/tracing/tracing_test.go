@@ -0,0 +1,113 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/tracing"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace context sent by the client in the tests.
+const (
+	remoteTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	remoteSpanID      = "00f067aa0ba902b7"
+	remoteTraceparent = "00-" + remoteTraceID + "-" + remoteSpanID + "-01"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Parallel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(config.TracingConfig{SampleRatio: 0}, sdktrace.NewSimpleSpanProcessor(exporter))
+	defer provider.Shutdown(context.Background())
+	tracer := provider.Tracer("test")
+
+	t.Run("should sample new traces by ratio", func(t *testing.T) {
+		_, span := tracer.Start(context.Background(), "root")
+		span.End()
+
+		assert.False(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("should keep the sampling decision of the parent", func(t *testing.T) {
+		traceID, _ := trace.TraceIDFromHex(remoteTraceID)
+		spanID, _ := trace.SpanIDFromHex(remoteSpanID)
+		parent := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, Remote: true})
+
+		_, span := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), parent), "child")
+		span.End()
+
+		require.Len(t, exporter.GetSpans(), 1)
+		assert.Equal(t, traceID, exporter.GetSpans()[0].SpanContext.TraceID())
+
+		serviceName, ok := exporter.GetSpans()[0].Resource.Set().Value(semconv.ServiceNameKey)
+		assert.True(t, ok)
+		assert.Equal(t, tracing.ServiceName, serviceName.AsString())
+	})
+}
+
+func TestNewInterceptor(t *testing.T) {
+	// Arrange: the interceptor records to the global tracer provider
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(config.TracingConfig{SampleRatio: 1}, sdktrace.NewSimpleSpanProcessor(exporter))
+	defer provider.Shutdown(context.Background())
+	otel.SetTracerProvider(provider)
+	_, err := tracing.Setup(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+
+	interceptor, err := tracing.NewInterceptor()
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.Handle(basicV1connect.NewBasicServiceHandler(internal.NewBasicServiceV1(config.Default().Background), connect.WithInterceptors(interceptor)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := basicV1connect.NewBasicServiceClient(server.Client(), server.URL)
+
+	t.Run("should continue the trace of the caller in a server span", func(t *testing.T) {
+		exporter.Reset()
+		req := connect.NewRequest(&basicServiceV1.HelloRequest{Message: "World"})
+		req.Header().Set("traceparent", remoteTraceparent)
+
+		// Act
+		resp, err := client.Hello(context.Background(), req)
+		require.NoError(t, err)
+
+		// Assert
+		require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 1 }, time.Second, 10*time.Millisecond)
+		span := exporter.GetSpans()[0]
+		assert.Equal(t, "basic.v1.BasicService/Hello", span.Name)
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+		assert.Equal(t, remoteTraceID, span.SpanContext.TraceID().String())
+		assert.Equal(t, remoteSpanID, span.Parent.SpanID().String())
+
+		traceparent := resp.Msg.CloudEvent.Attributes["traceparent"].GetCeString()
+		assert.Equal(t, "00-"+remoteTraceID+"-"+span.SpanContext.SpanID().String()+"-01", traceparent)
+	})
+
+	t.Run("should start a new trace without a caller trace", func(t *testing.T) {
+		exporter.Reset()
+
+		resp, err := client.Hello(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: "World"}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 1 }, time.Second, 10*time.Millisecond)
+		span := exporter.GetSpans()[0]
+		assert.False(t, span.Parent.IsValid())
+		assert.Contains(t, resp.Msg.CloudEvent.Attributes["traceparent"].GetCeString(), span.SpanContext.TraceID().String())
+	})
+}
@@ -1,19 +1,23 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
 	cloudeventsV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/io/cloudevents/v1"
+	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // CreateCloudEvent wraps a gRPC request and event data into a CloudEvents v1.0 compliant structure.
 // The Cloud Event includes metadata from the original request such as source procedure and host,
-// along with a unique ID and timestamp. Returns an error if the request cannot be converted.
-func CreateCloudEvent(request any, event *anypb.Any) (*cloudeventsV1.CloudEvent, error) {
+// along with a unique ID and timestamp. If ctx carries a trace, its W3C trace context is added
+// as traceparent and tracestate attributes (CloudEvents distributed tracing extension) so event
+// consumers can correlate the event with the call. Returns an error if the request cannot be converted.
+func CreateCloudEvent(ctx context.Context, request any, event *anypb.Any) (*cloudeventsV1.CloudEvent, error) {
 	if req, ok := request.(connect.AnyRequest); ok {
 		ce := &cloudeventsV1.CloudEvent{
 			Id:          uuid.New().String(),
@@ -33,6 +37,17 @@ func CreateCloudEvent(request any, event *anypb.Any) (*cloudeventsV1.CloudEvent,
 			},
 		}
 
+		// Add distributed tracing extension attributes
+		carrier := propagation.MapCarrier{}
+		propagation.TraceContext{}.Inject(ctx, carrier)
+		for _, key := range carrier.Keys() {
+			ce.Attributes[key] = &cloudeventsV1.CloudEvent_CloudEventAttributeValue{
+				Attr: &cloudeventsV1.CloudEvent_CloudEventAttributeValue_CeString{
+					CeString: carrier.Get(key),
+				},
+			}
+		}
+
 		return ce, nil
 	}
 
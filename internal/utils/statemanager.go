@@ -2,29 +2,115 @@
 package utils
 
 import (
+	"container/list"
+	"context"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Reasons an operation was evicted for, as counted by StateManager.Evictions.
+const (
+	EvictedTTL      = "ttl"      // The operation finished longer than Retention.TTL ago
+	EvictedCapacity = "capacity" // More than Retention.MaxEntries operations finished
+)
+
+// Retention bounds the finished operations a StateManager keeps. Operations
+// still processing are never evicted.
+type Retention struct {
+	TTL        time.Duration     // Age after completion at which Sweep evicts an operation, 0 keeps them
+	MaxEntries int               // Finished operations kept, the earliest finished are evicted first, 0 for no limit
+	Now        func() time.Time  // Clock of timestamps and ages, time.Now if nil
+	OnEvict    func(hash string) // Optional, called after an operation was evicted, without locks held
+}
+
 // StateManager tracks the lifecycle of background operations using unique hash identifiers.
-// It maintains state, timestamps, and errors for concurrent operations in a thread-safe manner.
+// It maintains state, timestamps, errors and process results for concurrent operations in a
+// thread-safe manner. Reads return copies, so callers never share memory with writers.
+//
+// Finished operations are kept as bounded by its Retention and evicted in the
+// order they finished. Reading an operation does not change that order.
 type StateManager struct {
-	mu       sync.Mutex
-	state    map[string]*basicServiceV1.State
-	start    map[string]*timestamppb.Timestamp
-	complete map[string]*timestamppb.Timestamp
-	errors   map[string]*[]error
+	retention Retention
+
+	mu        sync.Mutex
+	state     map[string]*basicServiceV1.State
+	start     map[string]*timestamppb.Timestamp
+	complete  map[string]*timestamppb.Timestamp
+	errors    map[string]*[]error
+	results   map[string][]*basicServiceV1.ProcessResult
+	watchers  map[string]map[chan struct{}]struct{}
+	finished  *list.List               // Hashes of finished operations, most recently finished first
+	elements  map[string]*list.Element // Elements of finished by hash
+	evictions map[string]uint64        // Evicted operations by reason
 }
 
-// NewStateManager creates a new StateManager with initialized internal maps.
+// NewStateManager creates a new StateManager with initialized internal maps
+// that keeps finished operations forever.
 func NewStateManager() *StateManager {
+	return NewStateManagerWithRetention(Retention{})
+}
+
+// NewStateManagerWithRetention creates a new StateManager keeping finished
+// operations as bounded by retention.
+func NewStateManagerWithRetention(retention Retention) *StateManager {
+	if retention.Now == nil {
+		retention.Now = time.Now
+	}
 	return &StateManager{
-		state:    make(map[string]*basicServiceV1.State),
-		start:    make(map[string]*timestamppb.Timestamp),
-		complete: make(map[string]*timestamppb.Timestamp),
-		errors:   make(map[string]*[]error),
+		retention: retention,
+		state:     make(map[string]*basicServiceV1.State),
+		start:     make(map[string]*timestamppb.Timestamp),
+		complete:  make(map[string]*timestamppb.Timestamp),
+		errors:    make(map[string]*[]error),
+		results:   make(map[string][]*basicServiceV1.ProcessResult),
+		watchers:  make(map[string]map[chan struct{}]struct{}),
+		finished:  list.New(),
+		elements:  make(map[string]*list.Element),
+		evictions: map[string]uint64{EvictedTTL: 0, EvictedCapacity: 0},
+	}
+}
+
+// Watch returns a channel receiving a notification immediately and then after
+// every change of the operation: its state, errors or results. Notifications
+// of changes made before the previous one was received are merged, so slow
+// receivers read the latest state once. The returned function stops the watch.
+func (m *StateManager) Watch(hash string) (<-chan struct{}, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	if m.watchers[hash] == nil {
+		m.watchers[hash] = map[chan struct{}]struct{}{}
+	}
+	m.watchers[hash][changes] = struct{}{}
+
+	return changes, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.watchers[hash], changes)
+		if len(m.watchers[hash]) == 0 {
+			delete(m.watchers, hash)
+		}
+	}
+}
+
+// notify tells the watchers of hash that the operation changed. It must be
+// called with m.mu held.
+func (m *StateManager) notify(hash string) {
+	for changes := range m.watchers[hash] {
+		// A pending notification already covers this change
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
 	}
 }
 
@@ -36,26 +122,88 @@ func (m *StateManager) Start(hash string) {
 
 	state := basicServiceV1.State_STATE_PROCESS
 	m.state[hash] = &state
-	m.start[hash] = timestamppb.Now()
+	m.start[hash] = timestamppb.New(m.retention.Now())
+	if element, ok := m.elements[hash]; ok {
+		m.finished.Remove(element)
+		delete(m.elements, hash)
+	}
+	m.notify(hash)
 }
 
 // Finish completes an operation by setting the final state based on error conditions
 // and recording the completion timestamp. Operations with errors are marked as
-// STATE_COMPLETE_WITH_ERROR, otherwise STATE_COMPLETE.
+// STATE_COMPLETE_WITH_ERROR, otherwise STATE_COMPLETE. Operations that already
+// ended through Cancel or Fail keep their state. The earliest finished
+// operations are evicted beyond Retention.MaxEntries.
 func (m *StateManager) Finish(hash string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	var state basicServiceV1.State
-	errors, exists := m.errors[hash]
-	if exists && errors != nil && len(*errors) > 0 {
+	if state, ok := m.state[hash]; ok && (*state == basicServiceV1.State_STATE_CANCELLED || *state == basicServiceV1.State_STATE_ERROR) {
+		m.mu.Unlock()
+		return
+	}
+	state := basicServiceV1.State_STATE_COMPLETE
+	if errors, exists := m.errors[hash]; exists && errors != nil && len(*errors) > 0 {
 		state = basicServiceV1.State_STATE_COMPLETE_WITH_ERROR
-	} else {
-		state = basicServiceV1.State_STATE_COMPLETE
 	}
+	evicted := m.finish(hash, state)
+	m.mu.Unlock()
+	m.evicted(evicted)
+}
 
+// finish records state as the final state of hash and returns the operations
+// evicted to stay within Retention.MaxEntries. It must be called with m.mu held.
+func (m *StateManager) finish(hash string, state basicServiceV1.State) []string {
 	m.state[hash] = &state
-	m.complete[hash] = timestamppb.Now()
+	m.complete[hash] = timestamppb.New(m.retention.Now())
+	m.notify(hash)
+
+	if element, ok := m.elements[hash]; ok {
+		m.finished.MoveToFront(element)
+	} else {
+		m.elements[hash] = m.finished.PushFront(hash)
+	}
+
+	var evicted []string
+	for m.retention.MaxEntries > 0 && m.finished.Len() > m.retention.MaxEntries {
+		oldest := m.finished.Back().Value.(string)
+		m.evict(oldest, EvictedCapacity)
+		evicted = append(evicted, oldest)
+	}
+	return evicted
+}
+
+// Cancel ends a processing operation by setting its state to STATE_CANCELLED
+// and recording the completion timestamp. It returns false without changes if
+// the operation is unknown or no longer processing.
+func (m *StateManager) Cancel(hash string) bool {
+	return m.end(hash, basicServiceV1.State_STATE_CANCELLED, nil)
+}
+
+// Fail ends a processing operation that cannot continue, e.g. after a panic,
+// by recording err and setting its state to STATE_ERROR. It returns false
+// without changes if the operation is unknown or no longer processing.
+func (m *StateManager) Fail(hash string, err error) bool {
+	return m.end(hash, basicServiceV1.State_STATE_ERROR, err)
+}
+
+// end sets the final state of a processing operation, recording err if not
+// nil, and reports whether it was processing.
+func (m *StateManager) end(hash string, state basicServiceV1.State, err error) bool {
+	m.mu.Lock()
+	if current, ok := m.state[hash]; !ok || *current != basicServiceV1.State_STATE_PROCESS {
+		m.mu.Unlock()
+		return false
+	}
+	if err != nil {
+		if _, exists := m.errors[hash]; !exists {
+			m.errors[hash] = &[]error{}
+		}
+		*m.errors[hash] = append(*m.errors[hash], err)
+	}
+	evicted := m.finish(hash, state)
+	m.mu.Unlock()
+	m.evicted(evicted)
+	return true
 }
 
 // GetState returns the current state, start time, and completion time for the given hash.
@@ -77,6 +225,7 @@ func (m *StateManager) SetError(hash string, err error) {
 			m.errors[hash] = &[]error{}
 		}
 		*m.errors[hash] = append(*m.errors[hash], err)
+		m.notify(hash)
 	}
 }
 
@@ -101,5 +250,153 @@ func (m *StateManager) GetErrors(hash string) []error {
 	if !exists || errors == nil {
 		return []error{}
 	}
-	return *errors
+	return slices.Clone(*errors)
+}
+
+// AppendResult records the result of a finished process of the operation. The
+// result is copied, so the caller may keep using it.
+func (m *StateManager) AppendResult(hash string, result *basicServiceV1.ProcessResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.results[hash] = append(m.results[hash], proto.Clone(result).(*basicServiceV1.ProcessResult))
+	m.notify(hash)
+}
+
+// Results returns copies of the results recorded for the operation in the order
+// they were appended, or an empty slice if none exist.
+func (m *StateManager) Results(hash string) []*basicServiceV1.ProcessResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]*basicServiceV1.ProcessResult, len(m.results[hash]))
+	for i, result := range m.results[hash] {
+		results[i] = proto.Clone(result).(*basicServiceV1.ProcessResult)
+	}
+	return results
+}
+
+// evict forgets the finished operation hash and counts it for reason. Its
+// watchers are notified and find it gone. It must be called with m.mu held.
+func (m *StateManager) evict(hash, reason string) {
+	m.notify(hash)
+	if element, ok := m.elements[hash]; ok {
+		m.finished.Remove(element)
+		delete(m.elements, hash)
+	}
+	delete(m.state, hash)
+	delete(m.start, hash)
+	delete(m.complete, hash)
+	delete(m.errors, hash)
+	delete(m.results, hash)
+	delete(m.watchers, hash)
+	m.evictions[reason]++
+}
+
+// evicted reports the evicted operations to Retention.OnEvict. It must be
+// called without m.mu held.
+func (m *StateManager) evicted(hashes []string) {
+	if m.retention.OnEvict == nil {
+		return
+	}
+	for _, hash := range hashes {
+		m.retention.OnEvict(hash)
+	}
+}
+
+// Sweep evicts the operations that finished longer than Retention.TTL ago and
+// returns their number. It does nothing without a TTL.
+func (m *StateManager) Sweep() int {
+	if m.retention.TTL <= 0 {
+		return 0
+	}
+
+	m.mu.Lock()
+	cutoff := m.retention.Now().Add(-m.retention.TTL)
+	var evicted []string
+	for hash := range m.elements {
+		if m.complete[hash].AsTime().Before(cutoff) {
+			evicted = append(evicted, hash)
+		}
+	}
+	for _, hash := range evicted {
+		m.evict(hash, EvictedTTL)
+	}
+	m.mu.Unlock()
+
+	m.evicted(evicted)
+	return len(evicted)
+}
+
+// RunJanitor sweeps expired operations every interval until ctx is cancelled.
+// A non-positive interval or a Retention without TTL disables sweeping.
+func (m *StateManager) RunJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || m.retention.TTL <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep()
+		}
+	}
+}
+
+// Evictions returns the number of operations evicted so far by reason,
+// EvictedTTL or EvictedCapacity.
+func (m *StateManager) Evictions() map[string]uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.evictions)
+}
+
+// Snapshot is a point-in-time copy of the tracked state of a single operation.
+type Snapshot struct {
+	Hash     string     `json:"hash"`
+	State    string     `json:"state"`
+	Start    *time.Time `json:"start,omitempty"`
+	Complete *time.Time `json:"complete,omitempty"`
+	Errors   []string   `json:"errors,omitempty"`
+}
+
+// Snapshot returns a copy of all tracked operations ordered by start time, so
+// callers can inspect them without holding the lock.
+func (m *StateManager) Snapshot() []Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshots := make([]Snapshot, 0, len(m.state))
+	for hash, state := range m.state {
+		snapshot := Snapshot{Hash: hash, State: state.String()}
+		if start, ok := m.start[hash]; ok {
+			t := start.AsTime()
+			snapshot.Start = &t
+		}
+		if complete, ok := m.complete[hash]; ok {
+			t := complete.AsTime()
+			snapshot.Complete = &t
+		}
+		if errs, ok := m.errors[hash]; ok && errs != nil {
+			for _, err := range *errs {
+				snapshot.Errors = append(snapshot.Errors, err.Error())
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if a.Start == nil || b.Start == nil || a.Start.Equal(*b.Start) {
+			return a.Hash < b.Hash
+		}
+		return a.Start.Before(*b.Start)
+	})
+	return snapshots
 }
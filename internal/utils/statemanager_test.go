@@ -1,11 +1,14 @@
 package utils_test
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -138,4 +141,341 @@ func TestUpdateState(t *testing.T) {
 		assert.NotEmpty(t, errors)
 		assert.Len(t, errors, 2)
 	})
+
+	t.Run("should cancel processing operations only", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("running")
+		sm.Start("finished")
+		sm.Finish("finished")
+
+		assert.True(t, sm.Cancel("running"))
+		assert.False(t, sm.Cancel("finished"))
+		assert.False(t, sm.Cancel("unknown"))
+
+		state, _, complete := sm.GetState("running")
+		assert.Equal(t, "STATE_CANCELLED", state.String())
+		assert.NotNil(t, complete)
+		state, _, _ = sm.GetState("finished")
+		assert.Equal(t, "STATE_COMPLETE", state.String())
+	})
+
+	t.Run("should fail processing operations only", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("running")
+		sm.Start("cancelled")
+		sm.Cancel("cancelled")
+
+		assert.True(t, sm.Fail("running", errors.New("panic")))
+		assert.False(t, sm.Fail("cancelled", errors.New("panic")))
+		assert.False(t, sm.Fail("unknown", errors.New("panic")))
+
+		state, _, complete := sm.GetState("running")
+		assert.Equal(t, "STATE_ERROR", state.String())
+		assert.NotNil(t, complete)
+		assert.Len(t, sm.GetErrors("running"), 1)
+		assert.Empty(t, sm.GetErrors("cancelled"))
+	})
+
+	t.Run("should keep the state of cancelled and failed operations when finished", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("cancelled")
+		sm.Cancel("cancelled")
+		sm.Start("failed")
+		sm.Fail("failed", errors.New("panic"))
+
+		sm.Finish("cancelled")
+		sm.Finish("failed")
+
+		state, _, _ := sm.GetState("cancelled")
+		assert.Equal(t, "STATE_CANCELLED", state.String())
+		state, _, _ = sm.GetState("failed")
+		assert.Equal(t, "STATE_ERROR", state.String())
+	})
+
+	t.Run("should return copies of the recorded results", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("hash")
+		result := &basicServiceV1.ProcessResult{Process: 1, Service: "service-1", State: basicServiceV1.State_STATE_COMPLETE}
+
+		sm.AppendResult("hash", result)
+		result.State = basicServiceV1.State_STATE_ERROR
+		sm.Results("hash")[0].Service = "changed"
+
+		results := sm.Results("hash")
+		assert.Len(t, results, 1)
+		assert.Equal(t, "service-1", results[0].GetService())
+		assert.Equal(t, basicServiceV1.State_STATE_COMPLETE, results[0].GetState())
+		assert.Empty(t, sm.Results("unknown"))
+	})
+
+	t.Run("should record and read results concurrently", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("hash")
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				sm.AppendResult("hash", &basicServiceV1.ProcessResult{Process: int64(i)})
+			}()
+			go func() {
+				defer wg.Done()
+				for _, result := range sm.Results("hash") {
+					_ = result.GetProcess()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, sm.Results("hash"), 50)
+	})
+
+	t.Run("should notify watchers of changes", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		changes, stop := sm.Watch("hash")
+		defer stop()
+
+		assert.Len(t, changes, 1, "current state is announced immediately")
+		<-changes
+
+		sm.Start("hash")
+		sm.AppendResult("hash", &basicServiceV1.ProcessResult{})
+		sm.Finish("hash")
+		assert.Len(t, changes, 1, "pending changes are merged")
+		<-changes
+
+		sm.Start("other")
+		assert.Empty(t, changes)
+	})
+
+	t.Run("should not notify stopped watchers", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		changes, stop := sm.Watch("hash")
+		<-changes
+
+		stop()
+		sm.Start("hash")
+		assert.Empty(t, changes)
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	t.Run("should return an empty snapshot without operations", func(t *testing.T) {
+		sm := utils.NewStateManager()
+
+		assert.Empty(t, sm.Snapshot())
+	})
+
+	t.Run("should copy state, timestamps and errors of all operations", func(t *testing.T) {
+		sm := utils.NewStateManager()
+
+		sm.Start("first")
+		sm.SetError("first", errors.New("test error"))
+		sm.Finish("first")
+		sm.Start("second")
+
+		snapshot := sm.Snapshot()
+		assert.Len(t, snapshot, 2)
+
+		byHash := map[string]utils.Snapshot{}
+		for _, s := range snapshot {
+			byHash[s.Hash] = s
+		}
+
+		assert.Equal(t, "STATE_COMPLETE_WITH_ERROR", byHash["first"].State)
+		assert.NotNil(t, byHash["first"].Start)
+		assert.NotNil(t, byHash["first"].Complete)
+		assert.Equal(t, []string{"test error"}, byHash["first"].Errors)
+
+		assert.Equal(t, "STATE_PROCESS", byHash["second"].State)
+		assert.NotNil(t, byHash["second"].Start)
+		assert.Nil(t, byHash["second"].Complete)
+		assert.Empty(t, byHash["second"].Errors)
+	})
+
+	t.Run("should not change when operations progress", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		sm.Start("hash")
+
+		snapshot := sm.Snapshot()
+		sm.Finish("hash")
+
+		assert.Equal(t, "STATE_PROCESS", snapshot[0].State)
+		assert.Nil(t, snapshot[0].Complete)
+	})
+}
+
+// clock is a manually advanced clock for Retention.Now.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should keep finished operations without retention", func(t *testing.T) {
+		sm := utils.NewStateManager()
+		for _, hash := range []string{"a", "b", "c"} {
+			sm.Start(hash)
+			sm.Finish(hash)
+		}
+
+		assert.Equal(t, 0, sm.Sweep())
+		assert.Len(t, sm.Snapshot(), 3)
+	})
+
+	t.Run("should stamp operations with the injected clock", func(t *testing.T) {
+		c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+		sm := utils.NewStateManagerWithRetention(utils.Retention{Now: c.Now})
+
+		sm.Start("hash")
+		c.Advance(time.Minute)
+		sm.Finish("hash")
+
+		_, start, complete := sm.GetState("hash")
+		assert.Equal(t, c.now.Add(-time.Minute), start.AsTime())
+		assert.Equal(t, c.now, complete.AsTime())
+	})
+
+	t.Run("should evict operations finished longer than the TTL ago", func(t *testing.T) {
+		c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+		var evicted []string
+		sm := utils.NewStateManagerWithRetention(utils.Retention{
+			TTL:     time.Hour,
+			Now:     c.Now,
+			OnEvict: func(hash string) { evicted = append(evicted, hash) },
+		})
+
+		sm.Start("old")
+		sm.SetError("old", errors.New("test error"))
+		sm.AppendResult("old", &basicServiceV1.ProcessResult{})
+		sm.Finish("old")
+		c.Advance(30 * time.Minute)
+		sm.Start("recent")
+		sm.Finish("recent")
+		sm.Start("running")
+		c.Advance(31 * time.Minute)
+
+		assert.Equal(t, 1, sm.Sweep())
+
+		state, start, complete := sm.GetState("old")
+		assert.Nil(t, state)
+		assert.Nil(t, start)
+		assert.Nil(t, complete)
+		assert.Empty(t, sm.GetErrors("old"))
+		assert.Empty(t, sm.Results("old"))
+		assert.Equal(t, []string{"old"}, evicted)
+		assert.Len(t, sm.Snapshot(), 2)
+
+		c.Advance(24 * time.Hour)
+		assert.Equal(t, 1, sm.Sweep())
+		state, _, _ = sm.GetState("running")
+		assert.Equal(t, "STATE_PROCESS", state.String(), "running operations are never evicted")
+		assert.Equal(t, map[string]uint64{utils.EvictedTTL: 2, utils.EvictedCapacity: 0}, sm.Evictions())
+	})
+
+	t.Run("should evict the earliest finished operations beyond the limit", func(t *testing.T) {
+		var evicted []string
+		sm := utils.NewStateManagerWithRetention(utils.Retention{
+			MaxEntries: 2,
+			OnEvict:    func(hash string) { evicted = append(evicted, hash) },
+		})
+		sm.Start("running")
+		for _, hash := range []string{"a", "b"} {
+			sm.Start(hash)
+			sm.Finish(hash)
+		}
+
+		sm.Start("c")
+		assert.Empty(t, evicted, "running operations do not count towards the limit")
+		assert.True(t, sm.Cancel("c"))
+
+		assert.Equal(t, []string{"a"}, evicted)
+		state, _, _ := sm.GetState("running")
+		assert.Equal(t, "STATE_PROCESS", state.String())
+		assert.Equal(t, map[string]uint64{utils.EvictedTTL: 0, utils.EvictedCapacity: 1}, sm.Evictions())
+
+		sm.Finish("running")
+		assert.Equal(t, []string{"a", "b"}, evicted)
+	})
+
+	t.Run("should not keep operations around for being read", func(t *testing.T) {
+		var evicted []string
+		sm := utils.NewStateManagerWithRetention(utils.Retention{
+			MaxEntries: 2,
+			OnEvict:    func(hash string) { evicted = append(evicted, hash) },
+		})
+		for _, hash := range []string{"a", "b"} {
+			sm.Start(hash)
+			sm.Finish(hash)
+		}
+
+		sm.GetState("a")
+		sm.GetErrors("a")
+		sm.Results("a")
+		sm.Start("c")
+		sm.Finish("c")
+
+		assert.Equal(t, []string{"a"}, evicted)
+	})
+
+	t.Run("should notify and drop the watchers of evicted operations", func(t *testing.T) {
+		sm := utils.NewStateManagerWithRetention(utils.Retention{MaxEntries: 1})
+		sm.Start("hash")
+		sm.Finish("hash")
+		changes, stop := sm.Watch("hash")
+		defer stop()
+		<-changes
+
+		sm.Start("other")
+		sm.Finish("other")
+
+		assert.Len(t, changes, 1)
+		state, _, _ := sm.GetState("hash")
+		assert.Nil(t, state)
+	})
+
+	t.Run("should sweep until the janitor is stopped", func(t *testing.T) {
+		c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+		sm := utils.NewStateManagerWithRetention(utils.Retention{TTL: time.Minute, Now: c.Now})
+		sm.Start("hash")
+		sm.Finish("hash")
+		c.Advance(2 * time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			sm.RunJanitor(ctx, time.Millisecond)
+		}()
+
+		assert.Eventually(t, func() bool { return len(sm.Snapshot()) == 0 }, time.Second, time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("janitor did not stop")
+		}
+	})
+
+	t.Run("should not run the janitor without TTL", func(t *testing.T) {
+		sm := utils.NewStateManager()
+
+		sm.RunJanitor(context.Background(), time.Millisecond) // Returns right away
+	})
 }
@@ -1,24 +1,62 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/recovery"
 	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName identifies the spans of the simulated downstream calls.
+const tracerName = "github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+
+// tracer returns the tracer of the span in ctx, so child spans are recorded by
+// the same provider as their parent.
+func tracer(ctx context.Context) trace.Tracer {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+}
+
 // CallService simulates an asynchronous service call with random delay.
 // Returns a channel that will receive a single response after 0-9 seconds
-// and then close. Used for testing fan-out patterns.
-func CallService(serviceName string, serviceType string) chan *basicServiceV1.SomeServiceResponse {
+// and then close, or close without a response once ctx is cancelled. Used for
+// testing fan-out patterns. The call is traced as a client span, a child of the
+// span in ctx, that ends with the response. A panic of the call closes the
+// channel and is reported to the recovery handler of ctx.
+func CallService(ctx context.Context, serviceName string, serviceType string) chan *basicServiceV1.SomeServiceResponse {
+	_, span := tracer(ctx).Start(ctx, "CallService",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("peer.service", serviceName),
+			attribute.String("basic.service_type", serviceType),
+		),
+	)
+
 	response := make(chan *basicServiceV1.SomeServiceResponse)
-	go func() {
+	recovery.Go(ctx, func() {
+		defer span.End()
+		defer close(response) // Also on panic, so the call ends without a response
+
 		// Simulate variable response time
 		n := rand.Intn(10)
-		time.Sleep(time.Duration(n) * time.Second)
+		timer := time.NewTimer(time.Duration(n) * time.Second)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		if err := ctx.Err(); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "call cancelled")
+			return
+		}
 
 		srvResp := &basicServiceV1.SomeServiceResponse{
 			Id:      uuid.NewString(),
@@ -30,37 +68,51 @@ func CallService(serviceName string, serviceType string) chan *basicServiceV1.So
 			},
 		}
 		response <- srvResp
-
-		close(response)
-	}()
+	})
 
 	return response
 }
 
+// ProcessResponses are the responses of the service call at index Process of
+// the channels passed to MergeServiceResponses.
+type ProcessResponses struct {
+	Process   int
+	Responses []*basicServiceV1.SomeServiceResponse
+}
+
 // MergeServiceResponses implements a fan-in pattern by collecting responses from
 // multiple service call channels. Each input channel's responses are grouped
-// and sent as a batch on the output channel. The output channel closes when
-// all input channels have been processed.
-func MergeServiceResponses(responses ...chan *basicServiceV1.SomeServiceResponse) chan *basicServiceV1.SomeServiceResponses {
+// and sent as a batch, tagged with the index of the channel, on the output
+// channel. The output channel closes when all input channels have been
+// processed. The fan-in is traced as a child of the span in ctx that ends when
+// the output channel closes. Panics are reported to the recovery handler of ctx.
+func MergeServiceResponses(ctx context.Context, responses ...chan *basicServiceV1.SomeServiceResponse) chan ProcessResponses {
+	_, span := tracer(ctx).Start(ctx, "MergeServiceResponses", trace.WithAttributes(attribute.Int("basic.calls", len(responses))))
+
 	var wg sync.WaitGroup
-	output := make(chan *basicServiceV1.SomeServiceResponses)
+	output := make(chan ProcessResponses)
 
 	wg.Add(len(responses))
-	for _, response := range responses {
-		go func(response <-chan *basicServiceV1.SomeServiceResponse) {
+	for i, response := range responses {
+		recovery.Go(ctx, func() {
 			defer wg.Done()
-			srvResponses := &basicServiceV1.SomeServiceResponses{}
+			srvResponses := ProcessResponses{Process: i}
 			for srvResp := range response {
 				srvResponses.Responses = append(srvResponses.Responses, srvResp)
 			}
+			span.AddEvent("responses received", trace.WithAttributes(
+				attribute.Int("basic.process", i),
+				attribute.Int("basic.responses", len(srvResponses.Responses)),
+			))
 			output <- srvResponses
-		}(response)
+		})
 	}
 
 	// Close output channel when all responses are processed
 	go func() {
 		wg.Wait()
 		close(output)
+		span.End()
 	}()
 
 	return output
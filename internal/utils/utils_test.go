@@ -0,0 +1,103 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/utils"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// traced returns a context carrying a span recorded to the returned exporter.
+func traced(t *testing.T) (context.Context, trace.Span, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "parent")
+	return ctx, span, exporter
+}
+
+func TestCallService(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should close without response once the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		select {
+		case response, ok := <-utils.CallService(ctx, "service", "rest"):
+			assert.False(t, ok)
+			assert.Nil(t, response)
+		case <-time.After(time.Second):
+			t.Fatal("call did not stop after cancellation")
+		}
+	})
+}
+
+func TestMergeServiceResponses(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should trace the fan-in as child of the caller", func(t *testing.T) {
+		ctx, parent, exporter := traced(t)
+		responses := make([]chan *basicServiceV1.SomeServiceResponse, 2)
+		for i := range responses {
+			responses[i] = make(chan *basicServiceV1.SomeServiceResponse, 1)
+			responses[i] <- &basicServiceV1.SomeServiceResponse{Name: "service"}
+			close(responses[i])
+		}
+
+		var batches int
+		for range utils.MergeServiceResponses(ctx, responses...) {
+			batches++
+		}
+		assert.Equal(t, 2, batches)
+
+		require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 1 }, time.Second, 10*time.Millisecond)
+		span := exporter.GetSpans()[0]
+		assert.Equal(t, "MergeServiceResponses", span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Len(t, span.Events, 2)
+	})
+}
+
+func TestCreateCloudEvent(t *testing.T) {
+	t.Parallel()
+
+	event, err := anypb.New(&basicServiceV1.HelloResponseEvent{Greeting: "Hello"})
+	require.NoError(t, err)
+	req := connect.NewRequest(&basicServiceV1.HelloRequest{Message: "World"})
+
+	t.Run("should add the trace context of the call", func(t *testing.T) {
+		ctx, span, _ := traced(t)
+
+		ce, err := utils.CreateCloudEvent(ctx, req, event)
+		require.NoError(t, err)
+
+		traceparent := ce.Attributes["traceparent"].GetCeString()
+		assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", traceparent)
+	})
+
+	t.Run("should not add a trace context without a trace", func(t *testing.T) {
+		ce, err := utils.CreateCloudEvent(context.Background(), req, event)
+		require.NoError(t, err)
+
+		assert.NotContains(t, ce.Attributes, "traceparent")
+		assert.Contains(t, ce.Attributes, "time")
+	})
+
+	t.Run("should reject requests that are not Connect requests", func(t *testing.T) {
+		_, err := utils.CreateCloudEvent(context.Background(), "hello", event)
+		assert.Error(t, err)
+	})
+}
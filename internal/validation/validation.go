@@ -0,0 +1,109 @@
+// Package validation enforces the protovalidate constraints declared in the
+// protos on every request message before it reaches a handler.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+)
+
+// interceptor validates the messages received by handlers.
+type interceptor struct {
+	validator protovalidate.Validator
+}
+
+// NewInterceptor returns a Connect interceptor validating the request of unary
+// calls and every message received on streams. Invalid messages fail with
+// CodeInvalidArgument and an errdetails.BadRequest detail listing the violated
+// fields, messages without constraints always pass.
+func NewInterceptor() (connect.Interceptor, error) {
+	validator, err := protovalidate.New()
+	if err != nil {
+		return nil, fmt.Errorf("create validator: %w", err)
+	}
+	return interceptor{validator: validator}, nil
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.validate(req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &validatingConn{StreamingHandlerConn: conn, interceptor: i})
+	}
+}
+
+// validate returns the error reported to the client if msg violates its
+// constraints.
+func (i interceptor) validate(msg any) error {
+	message, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	err := i.validator.Validate(message)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *protovalidate.ValidationError
+	if !errors.As(err, &validationErr) {
+		// The constraints could not be compiled or evaluated, a bug in the protos.
+		return rpcerror.New(connect.CodeInternal, basicerrors.ReasonValidationFailed, fmt.Errorf("validate %s: %w",
+			message.ProtoReflect().Descriptor().Name(), err))
+	}
+
+	fieldViolations := make([]*errdetails.BadRequest_FieldViolation, 0, len(validationErr.Violations))
+	violations := make([]string, 0, len(validationErr.Violations))
+	for _, violation := range validationErr.Violations {
+		field := protovalidate.FieldPathString(violation.Proto.GetField())
+		fieldViolations = append(fieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Proto.GetMessage(),
+			Reason:      violation.Proto.GetRuleId(),
+		})
+		violations = append(violations, field+": "+violation.Proto.GetMessage())
+	}
+
+	connectErr := rpcerror.New(connect.CodeInvalidArgument, basicerrors.ReasonInvalidRequest, fmt.Errorf("invalid %s: %s",
+		message.ProtoReflect().Descriptor().Name(), strings.Join(violations, "; ")))
+	return rpcerror.BadRequest(connectErr, fieldViolations...)
+}
+
+// validatingConn validates every message received on a stream.
+type validatingConn struct {
+	connect.StreamingHandlerConn
+	interceptor interceptor
+}
+
+// Receive implements connect.StreamingHandlerConn.
+func (c *validatingConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return c.interceptor.validate(msg)
+}
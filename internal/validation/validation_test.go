@@ -0,0 +1,156 @@
+package validation_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/validation"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// badRequest returns the field violations of the BadRequest detail of err.
+func badRequest(t *testing.T, err error) []*errdetails.BadRequest_FieldViolation {
+	t.Helper()
+
+	details, ok := basicerrors.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, basicerrors.ReasonInvalidRequest, details.Reason)
+	require.NotEmpty(t, details.FieldViolations)
+	return details.FieldViolations
+}
+
+func TestNewInterceptor(t *testing.T) {
+	t.Parallel()
+
+	interceptor, err := validation.NewInterceptor()
+	require.NoError(t, err)
+
+	var handled int
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.TestService/Hello", connect.NewUnaryHandler(
+		"/test.v1.TestService/Hello",
+		func(context.Context, *connect.Request[basicServiceV1.HelloRequest]) (*connect.Response[emptypb.Empty], error) {
+			handled++
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	mux.Handle("/test.v1.TestService/Background", connect.NewServerStreamHandler(
+		"/test.v1.TestService/Background",
+		func(context.Context, *connect.Request[basicServiceV1.BackgroundRequest], *connect.ServerStream[emptypb.Empty]) error {
+			return nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	mux.Handle("/test.v1.TestService/Talk", connect.NewBidiStreamHandler(
+		"/test.v1.TestService/Talk",
+		func(_ context.Context, stream *connect.BidiStream[basicServiceV1.TalkRequest, emptypb.Empty]) error {
+			for {
+				if _, err := stream.Receive(); err != nil {
+					return err
+				}
+				if err := stream.Send(&emptypb.Empty{}); err != nil {
+					return err
+				}
+			}
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	hello := connect.NewClient[basicServiceV1.HelloRequest, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Hello")
+
+	t.Run("should pass valid requests to the handler", func(t *testing.T) {
+		_, err := hello.CallUnary(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: "World"}))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, handled)
+	})
+
+	t.Run("should reject invalid requests before the handler", func(t *testing.T) {
+		_, err := hello.CallUnary(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{}))
+
+		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "invalid HelloRequest: message: value length must be at least 1 characters")
+		assert.Equal(t, 1, handled)
+	})
+
+	t.Run("should report violated fields as BadRequest details", func(t *testing.T) {
+		_, err := hello.CallUnary(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: strings.Repeat("x", 257)}))
+
+		violations := badRequest(t, err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "message", violations[0].GetField())
+		assert.Equal(t, "string.max_len", violations[0].GetReason())
+		assert.Equal(t, "value length must be at most 256 characters", violations[0].GetDescription())
+	})
+
+	t.Run("should validate the request of server streams", func(t *testing.T) {
+		client := connect.NewClient[basicServiceV1.BackgroundRequest, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Background")
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{Processes: -1}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(stream.Err()))
+		violations := badRequest(t, stream.Err())
+		require.Len(t, violations, 1)
+		assert.Equal(t, "processes", violations[0].GetField())
+		assert.Equal(t, "int64.gte_lte", violations[0].GetReason())
+	})
+
+	t.Run("should reject heartbeat intervals below 100ms", func(t *testing.T) {
+		client := connect.NewClient[basicServiceV1.BackgroundRequest, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Background")
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{HeartbeatInterval: durationpb.New(time.Millisecond)}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		violations := badRequest(t, stream.Err())
+		require.Len(t, violations, 1)
+		assert.Equal(t, "heartbeat_interval", violations[0].GetField())
+		assert.Equal(t, "heartbeat_interval.min", violations[0].GetReason())
+	})
+
+	t.Run("should only accept UUIDs as job IDs", func(t *testing.T) {
+		client := connect.NewClient[basicServiceV1.BackgroundRequest, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Background")
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&basicServiceV1.BackgroundRequest{JobId: "job-1"}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.False(t, stream.Receive())
+		violations := badRequest(t, stream.Err())
+		require.Len(t, violations, 1)
+		assert.Equal(t, "job_id", violations[0].GetField())
+		assert.Equal(t, "string.uuid", violations[0].GetReason())
+	})
+
+	t.Run("should validate every message received on bidi streams", func(t *testing.T) {
+		client := connect.NewClient[basicServiceV1.TalkRequest, emptypb.Empty](server.Client(), server.URL+"/test.v1.TestService/Talk")
+		stream := client.CallBidiStream(context.Background())
+		defer stream.CloseResponse()
+
+		require.NoError(t, stream.Send(&basicServiceV1.TalkRequest{Message: "Hello"}))
+		_, err := stream.Receive()
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&basicServiceV1.TalkRequest{Message: strings.Repeat("x", 1025)}))
+		_, err = stream.Receive()
+		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "invalid TalkRequest: message: value length must be at most 1024 characters")
+	})
+}
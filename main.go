@@ -4,102 +4,529 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"connectrpc.com/connect"
-	"connectrpc.com/grpchealth"
 	"connectrpc.com/grpcreflect"
 	"github.com/quic-go/quic-go/http3"
 	"github.com/soundphilosopher/basic-grpc-service-go/internal"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/admin"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/authz"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/certs"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/health"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/listeners"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/metrics"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/ratelimit"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/recovery"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/rpcerror"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/tracing"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/validation"
 	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"golang.org/x/sync/errgroup"
 )
 
-// main starts both HTTP/2 and HTTP/3 servers concurrently on the same address.
-// Both servers serve the same gRPC services with TLS enabled.
+// Process exit codes reported by main.
+const (
+	exitOK           = 0 // Servers drained and stopped cleanly
+	exitFailure      = 1 // Servers failed to start or stopped unexpectedly
+	exitDrainTimeout = 2 // Drain deadline expired and remaining streams were aborted
+	exitUsage        = 3 // Invalid flags or configuration
+)
+
+// tracingFlushTimeout bounds exporting the spans still buffered on exit.
+const tracingFlushTimeout = 5 * time.Second
+
+// errDrainTimeout is returned by setupListeners when active streams did not
+// finish before the drain deadline and the servers had to be closed forcefully.
+var errDrainTimeout = errors.New("drain deadline exceeded")
+
+// main starts both HTTP/2 and HTTP/3 servers concurrently, by default on the same
+// address with TLS enabled. The HTTP/2 server can alternatively serve cleartext
+// HTTP/2 (h2c). On SIGINT or SIGTERM the servers are drained gracefully before
+// the process exits.
 func main() {
-	mux := setupMux()
-	addr := getServerAddress()
+	cfg, printConfig, err := loadConfig(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	if printConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print config: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		return
+	}
+
+	slog.SetDefault(logging.New(cfg.Log, os.Stderr))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(exitFailure)
+	}
+	tracingInterceptor, err := tracing.NewInterceptor()
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(exitFailure)
+	}
+
+	validationInterceptor, err := validation.NewInterceptor()
+	if err != nil {
+		slog.Error("Failed to set up request validation", "error", err)
+		os.Exit(exitFailure)
+	}
 
-	httpServer := createHTTP2Server(addr, mux)
-	defer httpServer.Close()
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		slog.Error("Failed to set up authentication", "error", err)
+		os.Exit(exitFailure)
+	}
+	var policy *authz.Policy
+	if cfg.Auth.PolicyFile != "" {
+		if policy, err = authz.LoadPolicy(cfg.Auth.PolicyFile); err != nil {
+			slog.Error("Failed to load authorization policy", "error", err)
+			os.Exit(exitFailure)
+		}
+	}
+
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
+	service := internal.NewBasicServiceV1(cfg.Background)
+	service.Health = checker
+	serviceMetrics := metrics.New(service.StateManager)
+	service.PanicRecovered = serviceMetrics.PanicRecovered
+	mux := setupMux(cfg, checker, service,
+		tracingInterceptor,
+		serviceMetrics.Interceptor(),
+		auth.NewInterceptor(authenticator, cfg.Auth.Exempt),
+		authz.NewInterceptor(policy, cfg.Auth.PolicyDryRun, cfg.Auth.Exempt),
+		ratelimit.NewInterceptor(cfg.Limits, serviceMetrics.Limited),
+		validationInterceptor,
+		recovery.NewInterceptor(serviceMetrics.PanicRecovered),
+	)
+	handler := logging.Handler(serviceMetrics.HTTPVersionHandler(certs.ClientIdentityHandler(mux)))
+
+	adminServer, err := startAdminServer(cfg, service, serviceMetrics)
+	if err != nil {
+		slog.Error("Failed to start admin server", "error", err)
+		os.Exit(exitFailure)
+	}
+
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
 
-	http3Server := createHTTP3Server(addr, mux)
-	defer http3Server.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	janitorDone := make(chan struct{})
+	go func() {
+		defer close(janitorDone)
+		service.StateManager.RunJanitor(ctx, cfg.Background.JanitorInterval)
+	}()
+	err = setupListeners(ctx, cfg, httpServer, http3Server, checker)
+	stop()
+	<-janitorDone
+	if adminServer != nil {
+		adminServer.Close()
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	cancelFlush()
+
+	if err != nil {
+		slog.Error("Server stopped", "error", err)
+	}
+	os.Exit(exitCode(err))
+}
 
-	if err := setupListeners(context.Background(), addr, &httpServer, &http3Server); err != nil {
-		log.Fatalf("failed to setup listeners: %v", err)
+// exitCode maps the result of setupListeners to a process exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errDrainTimeout):
+		return exitDrainTimeout
+	default:
+		return exitFailure
 	}
 }
 
-// setupMux configures the HTTP multiplexer with gRPC services, health checks,
-// and reflection handlers. All handlers use 1KB minimum compression.
-func setupMux() *http.ServeMux {
-	compress1KB := connect.WithCompressMinBytes(1024)
+// loadConfig resolves the effective configuration from the given command line
+// arguments, the environment and an optional configuration file. The returned
+// bool reports whether -print-config was requested.
+func loadConfig(args []string) (*config.Config, bool, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	printConfig := fs.Bool("print-config", false, "print the effective configuration as YAML and exit")
+
+	cfg, err := config.Load(fs, args, os.LookupEnv)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return cfg, *printConfig, nil
+}
+
+// setupMux configures the HTTP multiplexer with the gRPC service, health checks,
+// and reflection handlers. All handlers share the configured compression threshold,
+// log every call and run the given interceptors in order, e.g. to record metrics.
+// The last interceptor is closest to the handler.
+func setupMux(cfg *config.Config, checker *health.Checker, service *internal.BasicServiceV1, interceptors ...connect.Interceptor) *http.ServeMux {
+	options := connect.WithHandlerOptions(
+		connect.WithCompressMinBytes(cfg.Compression.MinBytes),
+		connect.WithInterceptors(append([]connect.Interceptor{logging.NewInterceptor(), rpcerror.NewInterceptor(cfg.Errors.DebugInfo)}, interceptors...)...),
+	)
 	mux := http.NewServeMux()
 
 	// Register core business service
-	mux.Handle(basicV1connect.NewBasicServiceHandler(internal.NewBasicServiceV1(), compress1KB))
+	mux.Handle(basicV1connect.NewBasicServiceHandler(service, options))
 
 	// Register health and reflection services
 	checkServices := []string{
 		basicV1connect.BasicServiceName,
 	}
-	mux.Handle(grpchealth.NewHandler(grpchealth.NewStaticChecker(checkServices...), compress1KB))
-	mux.Handle(grpcreflect.NewHandlerV1(grpcreflect.NewStaticReflector(checkServices...), compress1KB))
-	mux.Handle(grpcreflect.NewHandlerV1Alpha(grpcreflect.NewStaticReflector(checkServices...), compress1KB))
+	mux.Handle(health.NewHandler(checker, options))
+	mux.Handle(grpcreflect.NewHandlerV1(grpcreflect.NewStaticReflector(checkServices...), options))
+	mux.Handle(grpcreflect.NewHandlerV1Alpha(grpcreflect.NewStaticReflector(checkServices...), options))
 
 	return mux
 }
 
-// getServerAddress parses command line flags and returns the server bind address.
-// If -server-addr flag is not provided, defaults to "127.0.0.1:8443"
-func getServerAddress() string {
-	serverAddr := flag.String("server-addr", "127.0.0.1:8443", "server address to bind to")
-	flag.Parse()
+// startAdminServer starts the cleartext admin server on its own address if one is
+// configured, exposing pprof, Prometheus and expvar metrics, build info, the
+// effective configuration and the operations tracked by service. The address is bound before returning so
+// startup fails on bind errors. It returns nil if the admin server is disabled.
+// The admin server is not drained on shutdown, so it stays available while the
+// service listeners drain.
+func startAdminServer(cfg *config.Config, service *internal.BasicServiceV1, serviceMetrics *metrics.Metrics) (*http.Server, error) {
+	if cfg.Admin.Addr == "" {
+		return nil, nil
+	}
+
+	l, err := net.Listen("tcp", cfg.Admin.Addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{
+		Handler:           admin.NewHandler(cfg, service.StateManager, serviceMetrics.Handler()),
+		ReadHeaderTimeout: cfg.HTTP2.ReadHeaderTimeout,
+	}
+
+	slog.Info("Starting admin server", "addr", l.Addr().String())
+	go func() {
+		if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Admin server stopped", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// createHTTP2Server creates an HTTP/2 server with the configured timeouts. Over TLS
+// its responses advertise http3Server via Alt-Svc so capable clients can switch
+// to HTTP/3. In h2c mode the handler instead accepts cleartext HTTP/2 connections
+// with prior knowledge or via Upgrade.
+func createHTTP2Server(cfg *config.Config, handler http.Handler, http3Server *http3.Server) *http.Server {
+	if cfg.Server.Mode == config.ModeTLS && cfg.HTTP3.Enabled && cfg.HTTP3.AltSvc {
+		if altSvc, err := altSvcHeader(http3Server, cfg.HTTP3.AltSvcMaxAge); err != nil {
+			slog.Warn("Not advertising HTTP/3", "error", err)
+		} else {
+			handler = altSvcHandler(handler, altSvc)
+		}
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTP2.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP2.ReadTimeout,
+		WriteTimeout:      cfg.HTTP2.WriteTimeout,
+		MaxHeaderBytes:    cfg.HTTP2.MaxHeaderBytes,
+	}
+
+	if cfg.Server.Mode == config.ModeH2C {
+		h2s := &http2.Server{}
+		server.Handler = newH2CHandler(handler, h2s)
+		// Registers h2c connections with the server so Shutdown sends GOAWAY on
+		// them. It only fails for TLS cipher suites, which are not used here.
+		_ = http2.ConfigureServer(server, h2s)
+	}
+
+	return server
+}
+
+// altSvcHeader builds the Alt-Svc header value announcing http3Server. The port
+// is taken from http3Server.Port if set, otherwise from its bind address.
+func altSvcHeader(http3Server *http3.Server, maxAge time.Duration) (string, error) {
+	port := http3Server.Port
+	if port == 0 {
+		_, portStr, err := net.SplitHostPort(http3Server.Addr)
+		if err != nil {
+			return "", err
+		}
+		if port, err = strconv.Atoi(portStr); err != nil || port == 0 {
+			return "", fmt.Errorf("no port to advertise in %q", http3Server.Addr)
+		}
+	}
+
+	return fmt.Sprintf(`%s=":%d"; ma=%d`, http3.NextProtoH3, port, int(maxAge.Seconds())), nil
+}
+
+// altSvcHandler adds the Alt-Svc header value to every response of next.
+func altSvcHandler(next http.Handler, value string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Alt-Svc", value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// h2cHandler serves cleartext HTTP/2. h2c connections are hijacked from the
+// http.Server, so Shutdown does not wait for them; the handler counts them
+// itself to let shutdownServers drain them as well.
+type h2cHandler struct {
+	http.Handler
+	active atomic.Int64
+}
+
+// newH2CHandler wraps handler to accept h2c connections served by h2s.
+func newH2CHandler(handler http.Handler, h2s *http2.Server) *h2cHandler {
+	return &h2cHandler{Handler: h2c.NewHandler(handler, h2s)}
+}
+
+// ServeHTTP serves a request or, for h2c, a whole connection.
+func (h *h2cHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.active.Add(1)
+	defer h.active.Add(-1)
 
-	return *serverAddr
+	h.Handler.ServeHTTP(w, r)
 }
 
-// createHTTP2Server creates an HTTP/2 server with h2c support and reasonable timeouts.
-func createHTTP2Server(addr string, handler http.Handler) http.Server {
-	return http.Server{
-		Addr:              addr,
-		Handler:           h2c.NewHandler(handler, &http2.Server{}),
-		ReadHeaderTimeout: time.Second,
-		ReadTimeout:       5 * time.Minute,
-		WriteTimeout:      5 * time.Minute,
-		MaxHeaderBytes:    8 * 1024,
+// Wait blocks until all connections and requests served by the handler are
+// finished or ctx is done.
+func (h *h2cHandler) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for h.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
+	return nil
 }
 
 // createHTTP3Server creates an HTTP/3 server using QUIC protocol.
-func createHTTP3Server(addr string, handler http.Handler) http3.Server {
-	return http3.Server{
-		Addr:    addr,
+func createHTTP3Server(cfg *config.Config, handler http.Handler) *http3.Server {
+	return &http3.Server{
+		Addr:    cfg.HTTP3Addr(),
+		Port:    cfg.HTTP3.Port,
 		Handler: handler,
 	}
 }
 
-// setupListeners starts both HTTP/2 and HTTP/3 servers concurrently.
-// Returns an error if either server fails to start.
-func setupListeners(ctx context.Context, addr string, httpServer *http.Server, http3Server *http3.Server) error {
-	eg, _ := errgroup.WithContext(ctx)
+// setupListeners starts the HTTP/2 and, if enabled, the HTTP/3 server concurrently
+// and blocks until ctx is cancelled or either server fails. Besides their bind
+// addresses the servers accept connections on the Unix domain socket and the
+// sockets passed by systemd socket activation, if configured. TLS listeners share
+// the TLS configuration, including client certificate verification when mutual
+// TLS is enabled, and pick up rotated certificates without a restart. In h2c mode
+// the HTTP/2 server listens in cleartext. Health checks report NOT_SERVING until
+// all listeners are bound. On cancellation the servers are shut down via
+// shutdownServers. Returns an error if either server fails to start or
+// the drain deadline is exceeded.
+func setupListeners(ctx context.Context, cfg *config.Config, httpServer *http.Server, http3Server *http3.Server, checker *health.Checker) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	if cfg.UsesTLS() {
+		manager, err := newCertificateManager(cfg)
+		if err != nil {
+			return err
+		}
+		tlsConfig, err := certs.NewServerTLSConfig(cfg.TLS, manager)
+		if err != nil {
+			return err
+		}
+		httpServer.TLSConfig = tlsConfig
+		http3Server.TLSConfig = tlsConfig
+
+		eg.Go(func() error {
+			manager.Watch(egCtx)
+			return nil
+		})
+	}
+
+	set, err := openListeners(cfg)
+	if err != nil {
+		return err
+	}
+	defer set.Close()
+
+	for _, l := range set.Stream {
+		eg.Go(func() error {
+			var err error
+			if cfg.Server.Mode == config.ModeH2C {
+				slog.Info("Starting cleartext HTTP/2 (h2c) server", "network", l.Addr().Network(), "addr", l.Addr().String())
+				err = httpServer.Serve(l)
+			} else {
+				slog.Info("Starting HTTP/2 server", "network", l.Addr().Network(), "addr", l.Addr().String(), "client_auth", cfg.TLS.ClientAuth)
+				err = httpServer.ServeTLS(l, "", "")
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	for _, conn := range set.Packet {
+		if !cfg.HTTP3.Enabled {
+			slog.Warn("Ignoring datagram socket, HTTP/3 is disabled", "network", conn.LocalAddr().Network(), "addr", conn.LocalAddr().String())
+			continue
+		}
+		eg.Go(func() error {
+			slog.Info("Starting HTTP/3 server", "network", conn.LocalAddr().Network(), "addr", conn.LocalAddr().String(), "client_auth", cfg.TLS.ClientAuth)
+			if err := http3Server.Serve(conn); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	checker.Ready()
+	slog.Info("Servers are ready, reporting SERVING")
 
 	eg.Go(func() error {
-		log.Printf("Start HTTP over TCP server on %s ...", addr)
-		return httpServer.ListenAndServeTLS("./certs/local.crt", "./certs/local.key")
+		<-egCtx.Done()
+		return shutdownServers(httpServer, http3Server, checker, cfg.Server.DrainTimeout)
 	})
 
+	return eg.Wait()
+}
+
+// openListeners opens every socket the servers accept connections on: the TCP
+// and UDP bind addresses, the Unix domain socket and the sockets inherited via
+// systemd socket activation. Opening them up front reports bind errors before
+// any server starts. On error the sockets opened so far are closed.
+func openListeners(cfg *config.Config) (*listeners.Set, error) {
+	set := &listeners.Set{}
+
+	if cfg.Server.Addr != "" {
+		l, err := net.Listen("tcp", cfg.Server.Addr)
+		if err != nil {
+			return nil, err
+		}
+		set.Stream = append(set.Stream, l)
+	}
+
+	if cfg.HTTP3.Enabled && cfg.HTTP3Addr() != "" {
+		conn, err := net.ListenPacket("udp", cfg.HTTP3Addr())
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		set.Packet = append(set.Packet, conn)
+	}
+
+	if cfg.Server.UnixSocket != "" {
+		l, err := listeners.Unix(cfg.Server.UnixSocket, cfg.Server.UnixSocketPermissions())
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		set.Stream = append(set.Stream, l)
+	}
+
+	if cfg.Server.SocketActivation {
+		inherited, err := listeners.Systemd()
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		if len(inherited.Stream)+len(inherited.Packet) == 0 {
+			slog.Warn("Socket activation is enabled but no sockets were passed by systemd")
+		}
+		set.Add(inherited)
+	}
+
+	return set, nil
+}
+
+// newCertificateManager returns the manager serving the server certificate. In
+// development mode an ephemeral CA and certificate are generated in memory, the CA
+// fingerprint is logged and the CA is optionally written to disk for clients.
+// Otherwise the certificate is loaded from the configured files.
+func newCertificateManager(cfg *config.Config) (*certs.Manager, error) {
+	if !cfg.TLS.Dev {
+		return certs.NewManager(cfg.TLS)
+	}
+
+	dev, err := certs.GenerateDevelopment(cfg.Server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Generated development CA", "sha256_fingerprint", dev.Fingerprint())
+
+	if cfg.TLS.DevCAFile != "" {
+		if err := dev.WriteCA(cfg.TLS.DevCAFile); err != nil {
+			return nil, fmt.Errorf("write development CA: %w", err)
+		}
+		slog.Info("Wrote development CA", "path", cfg.TLS.DevCAFile)
+	}
+
+	return certs.NewStaticManager(dev.Leaf), nil
+}
+
+// shutdownServers drains both servers. Health checks are switched to NOT_SERVING
+// first so load balancers stop routing new calls, then the HTTP/2 server sends
+// GOAWAY and the HTTP/3 server its GOAWAY frame while active streams are allowed
+// to finish. Streams still running after drainTimeout are aborted and
+// errDrainTimeout is returned.
+func shutdownServers(httpServer *http.Server, http3Server *http3.Server, checker *health.Checker, drainTimeout time.Duration) error {
+	checker.Shutdown()
+
+	slog.Info("Shutting down, draining active streams", "drain_timeout", drainTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	var eg errgroup.Group
+	eg.Go(func() error {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			return err
+		}
+		if h2c, ok := httpServer.Handler.(*h2cHandler); ok {
+			return h2c.Wait(ctx)
+		}
+		return nil
+	})
 	eg.Go(func() error {
-		log.Printf("Start HTTP over UDP server on %s ...", addr)
-		return http3Server.ListenAndServeTLS("./certs/local.crt", "./certs/local.key")
+		return http3Server.Shutdown(ctx)
 	})
 
-	return eg.Wait()
+	err := eg.Wait()
+	if errors.Is(err, context.DeadlineExceeded) {
+		httpServer.Close()
+		http3Server.Close()
+		return errDrainTimeout
+	}
+	return err
 }
@@ -1,35 +1,86 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
 	"github.com/quic-go/quic-go/http3"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/auth"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/config"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/health"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/logging"
+	"github.com/soundphilosopher/basic-grpc-service-go/internal/metrics"
+	basicServiceV1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1"
+	"github.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1/basicV1connect"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/sync/errgroup"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
-func TestGetServerAddress(t *testing.T) {
-	t.Parallel()
-
+func TestLoadConfig(t *testing.T) {
 	t.Run("should return default address", func(t *testing.T) {
-		address := getServerAddress()
-		assert.Equal(t, "127.0.0.1:8443", address)
+		cfg, printConfig, err := loadConfig(nil)
+		require.NoError(t, err)
+		assert.False(t, printConfig)
+		assert.Equal(t, "127.0.0.1:8443", cfg.Server.Addr)
+	})
+
+	t.Run("should prefer flags over environment", func(t *testing.T) {
+		t.Setenv("BASIC_SERVER_ADDR", "127.0.0.1:9000")
+
+		cfg, _, err := loadConfig([]string{"-server-addr", "127.0.0.1:9001"})
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9001", cfg.Server.Addr)
+	})
+
+	t.Run("should report print-config mode", func(t *testing.T) {
+		_, printConfig, err := loadConfig([]string{"-print-config"})
+		require.NoError(t, err)
+		assert.True(t, printConfig)
+	})
+
+	t.Run("should reject invalid configuration", func(t *testing.T) {
+		_, _, err := loadConfig([]string{"-server-addr", "no-port"})
+		assert.ErrorContains(t, err, "server.addr")
 	})
 }
 
 func TestCreateHTTP2Server(t *testing.T) {
 	// Arrange
-	mux := setupMux()
-	addr := "127.0.0.1:0" // Use port 0 to bind to a random available port
-	httpServer := createHTTP2Server(addr, mux)
+	cfg := config.Default()
+	cfg.Server.Addr = "127.0.0.1:0" // Use port 0 to bind to a random available port
+	mux := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+	httpServer := createHTTP2Server(cfg, mux, createHTTP3Server(cfg, mux))
 
 	// Act
 	ln, err := net.Listen("tcp", httpServer.Addr)
@@ -49,9 +100,10 @@ func TestCreateHTTP2Server(t *testing.T) {
 
 func TestCreateHTTP3Server(t *testing.T) {
 	// Arrange
-	mux := setupMux()
-	addr := "127.0.0.1:0" // Random port
-	http3Server := createHTTP3Server(addr, mux)
+	cfg := config.Default()
+	cfg.Server.Addr = "127.0.0.1:0" // Random port
+	mux := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+	http3Server := createHTTP3Server(cfg, mux)
 
 	// Act
 	udpAddr, err := net.ResolveUDPAddr("udp", http3Server.Addr)
@@ -75,55 +127,749 @@ func TestCreateHTTP3Server(t *testing.T) {
 
 func TestSetupListenersWithSignalCancel(t *testing.T) {
 	// Arrange
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Channel to simulate receiving OS signals (like SIGINT for CTRL+C)
-	signalCh := make(chan os.Signal, 1)
-	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Set up a simple HTTP/2 server with a random port
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
 	mux := http.NewServeMux()
-	httpServer := &http.Server{
-		Addr:    "127.0.0.1:0", // Random port
-		Handler: mux,
-		TLSConfig: &tls.Config{
-			NextProtos: []string{"h2"},
-		},
+	cfg := testConfig(t)
+	cfg.Server.DrainTimeout = time.Second
+	http3Server := createHTTP3Server(cfg, mux)
+	httpServer := createHTTP2Server(cfg, mux, http3Server)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, checker)
+	}()
+
+	// Simulate the servers running for a short time (as if they were running normally)
+	time.Sleep(200 * time.Millisecond)
+
+	// Act: deliver SIGINT (CTRL+C) to the process
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+
+	// Assert: servers shut down gracefully and report NOT_SERVING
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not shut down after SIGINT")
 	}
-	ln, err := net.Listen("tcp", httpServer.Addr)
-	assert.NoError(t, err)
-	defer ln.Close()
 
-	// Create an HTTP/3 server bound to the same address
-	http3Server := &http3.Server{
-		Addr:    "127.0.0.1:0", // Random port for HTTP/3 as well
-		Handler: mux,
+	for _, service := range []string{"", basicV1connect.BasicServiceName} {
+		resp, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, grpchealth.StatusNotServing, resp.Status)
+	}
+}
+
+func TestSetupListenersHealth(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Server.Addr = freeAddress(t)
+	cfg.Server.Mode = config.ModeH2C
+	cfg.HTTP3.Enabled = false
+	cfg.Background.ProgressInterval = 50 * time.Millisecond
+	cfg.Background.SaturationThreshold = 1
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
+	service := internal.NewBasicServiceV1(cfg.Background)
+	service.Health = checker
+	handler := setupMux(cfg, checker, service)
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+	status := func() grpchealth.Status {
+		resp, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: basicV1connect.BasicServiceName})
+		require.NoError(t, err)
+		return resp.Status
 	}
+	updates, stopWatch := checker.Watch(basicV1connect.BasicServiceName)
+	defer stopWatch()
+
+	t.Run("should not serve before the listeners are bound", func(t *testing.T) {
+		assert.Equal(t, grpchealth.StatusNotServing, status())
+		assert.Equal(t, grpchealth.StatusNotServing, <-updates)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, checker)
+	}()
+	waitForListener(t, cfg.Server.Addr)
+
+	t.Run("should serve once the listeners are bound", func(t *testing.T) {
+		assert.Equal(t, grpchealth.StatusServing, <-updates)
+	})
+
+	t.Run("should not serve while Background is saturated", func(t *testing.T) {
+		httpClient := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+		client := basicV1connect.NewBasicServiceClient(httpClient, "http://"+cfg.Server.Addr, connect.WithGRPC())
+
+		streamCtx, cancelStream := context.WithCancel(context.Background())
+		defer cancelStream()
+		stream, err := client.Background(streamCtx, connect.NewRequest(&basicServiceV1.BackgroundRequest{}))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, grpchealth.StatusNotServing, <-updates)
+		assert.Equal(t, []string{"background: 1 of 1 jobs running"}, checker.Reasons(basicV1connect.BasicServiceName))
+	})
+
+	cancel()
+	assert.NoError(t, <-errCh)
+
+	t.Run("should not serve after shutdown", func(t *testing.T) {
+		assert.Equal(t, grpchealth.StatusNotServing, status())
+	})
+}
+
+func TestSetupListenersDrainsActiveRequests(t *testing.T) {
+	t.Run("should wait for in-flight requests to finish", func(t *testing.T) {
+		entered := make(chan struct{})
+		release := make(chan struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(entered)
+			<-release
+			w.WriteHeader(http.StatusOK)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cfg := testConfig(t)
+		cfg.Server.DrainTimeout = 5 * time.Second
+		addr := cfg.Server.Addr
+		http3Server := createHTTP3Server(cfg, mux)
+		httpServer := createHTTP2Server(cfg, mux, http3Server)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
+		}()
+
+		waitForListener(t, addr)
+
+		respCh := make(chan *http.Response, 1)
+		go func() {
+			resp, err := insecureClient().Get("https://" + addr + "/slow")
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+			respCh <- resp
+		}()
 
-	// Start the HTTP/2 server in the background
+		<-entered
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+
+		resp := <-respCh
+		assert.NoError(t, <-errCh)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	})
+
+	t.Run("should abort requests exceeding the drain deadline", func(t *testing.T) {
+		entered := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+			close(entered)
+			<-release
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cfg := testConfig(t)
+		cfg.Server.DrainTimeout = 100 * time.Millisecond
+		addr := cfg.Server.Addr
+		http3Server := createHTTP3Server(cfg, mux)
+		httpServer := createHTTP2Server(cfg, mux, http3Server)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
+		}()
+
+		waitForListener(t, addr)
+
+		go func() {
+			resp, err := insecureClient().Get("https://" + addr + "/stuck")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+
+		<-entered
+		cancel()
+
+		err := <-errCh
+		assert.ErrorIs(t, err, errDrainTimeout)
+		assert.Equal(t, exitDrainTimeout, exitCode(err))
+	})
+
+	t.Run("should end health Watch streams instead of waiting for the deadline", func(t *testing.T) {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName("connectext.grpc.health.v1.Health.Watch")
+		require.NoError(t, err)
+		method := desc.(protoreflect.MethodDescriptor)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cfg := testConfig(t)
+		cfg.Server.DrainTimeout = 5 * time.Second
+		addr := cfg.Server.Addr
+		checker := health.NewChecker(basicV1connect.BasicServiceName)
+		handler := setupMux(cfg, checker, internal.NewBasicServiceV1(cfg.Background))
+		http3Server := createHTTP3Server(cfg, handler)
+		httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- setupListeners(ctx, cfg, httpServer, http3Server, checker)
+		}()
+		waitForListener(t, addr)
+
+		client := connect.NewClient[dynamicpb.Message, dynamicpb.Message](
+			insecureClient(),
+			"https://"+addr+"/grpc.health.v1.Health/Watch",
+			connect.WithGRPC(),
+			connect.WithSchema(method),
+			connect.WithResponseInitializer(func(_ connect.Spec, msg any) error {
+				*msg.(*dynamicpb.Message) = *dynamicpb.NewMessage(method.Output())
+				return nil
+			}),
+		)
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(dynamicpb.NewMessage(method.Input())))
+		require.NoError(t, err)
+		defer stream.Close()
+		statusField := method.Output().Fields().ByName("status")
+		require.True(t, stream.Receive(), stream.Err())
+		assert.EqualValues(t, grpchealth.StatusServing, stream.Msg().Get(statusField).Enum())
+
+		begin := time.Now()
+		cancel()
+
+		require.True(t, stream.Receive(), stream.Err())
+		assert.EqualValues(t, grpchealth.StatusNotServing, stream.Msg().Get(statusField).Enum())
+		assert.False(t, stream.Receive(), "stream ends after NOT_SERVING")
+		assert.NoError(t, stream.Err())
+		assert.NoError(t, <-errCh)
+		assert.Less(t, time.Since(begin), time.Second)
+	})
+}
+
+func TestSetupListenersWithDevelopmentTLS(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Server.Addr = freeAddress(t)
+	cfg.TLS.Dev = true
+	cfg.TLS.DevCAFile = filepath.Join(t.TempDir(), "dev-ca.crt")
+	handler := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
+	}()
+	waitForListener(t, cfg.Server.Addr)
+
+	// Act: call Hello trusting only the written development CA
+	caPEM, err := os.ReadFile(cfg.TLS.DevCAFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caPEM))
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true}}
+	client := basicV1connect.NewBasicServiceClient(httpClient, "https://"+cfg.Server.Addr, connect.WithGRPC())
+	resp, err := client.Hello(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: "dev"}))
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotNil(t, resp.Msg.CloudEvent)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestSetupListenersWithH2C(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Server.Addr = freeAddress(t)
+	cfg.Server.Mode = config.ModeH2C
+	cfg.HTTP3.Enabled = false
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = "", "" // No certificate is needed without TLS listeners
+	handler := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
 	go func() {
-		_ = httpServer.ServeTLS(ln, "./certs/local.cert", "./certs/local.key")
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
 	}()
+	waitForListener(t, cfg.Server.Addr)
+
+	t.Run("should serve gRPC over cleartext HTTP/2 with prior knowledge", func(t *testing.T) {
+		httpClient := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+		client := basicV1connect.NewBasicServiceClient(httpClient, "http://"+cfg.Server.Addr, connect.WithGRPC())
+
+		resp, err := client.Hello(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: "mesh"}))
+		require.NoError(t, err)
+		assert.NotNil(t, resp.Msg.CloudEvent)
+	})
 
-	// Act: Start listeners using setupListeners
-	eg, egCtx := errgroup.WithContext(ctx)
+	t.Run("should upgrade HTTP/1.1 connections to h2c", func(t *testing.T) {
+		conn, err := net.Dial("tcp", cfg.Server.Addr)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n"))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Equal(t, "h2c", resp.Header.Get("Upgrade"))
+	})
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestSetupListenersWithUnixSocket(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Server.Addr = ""
+	cfg.Server.Mode = config.ModeH2C
+	cfg.Server.UnixSocket = filepath.Join(t.TempDir(), "basic.sock")
+	cfg.Server.UnixSocketMode = "0600"
+	cfg.HTTP3.Enabled = false
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = "", ""
+	handler := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
 	go func() {
-		err = setupListeners(egCtx, httpServer.Addr, httpServer, http3Server)
-		assert.NoError(t, err)
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
 	}()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", cfg.Server.UnixSocket)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
 
-	// Simulate the servers running for a short time (as if they were running normally)
-	time.Sleep(200 * time.Millisecond)
+	t.Run("should apply the configured permissions", func(t *testing.T) {
+		info, err := os.Stat(cfg.Server.UnixSocket)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("should serve gRPC over the Unix domain socket", func(t *testing.T) {
+		httpClient := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", cfg.Server.UnixSocket)
+			},
+		}}
+		client := basicV1connect.NewBasicServiceClient(httpClient, "http://localhost", connect.WithGRPC())
+
+		resp, err := client.Hello(context.Background(), connect.NewRequest(&basicServiceV1.HelloRequest{Message: "sidecar"}))
+		require.NoError(t, err)
+		assert.NotNil(t, resp.Msg.CloudEvent)
+	})
+
+	cancel()
+	assert.NoError(t, <-errCh)
+
+	t.Run("should remove the socket on shutdown", func(t *testing.T) {
+		_, err := os.Stat(cfg.Server.UnixSocket)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestAltSvc(t *testing.T) {
+	t.Run("should let clients discover and switch to HTTP/3", func(t *testing.T) {
+		// Arrange
+		mux := http.NewServeMux()
+		mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, r.Proto)
+		})
+		cfg := testConfig(t)
+		http3Server := createHTTP3Server(cfg, mux)
+		httpServer := createHTTP2Server(cfg, mux, http3Server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- setupListeners(ctx, cfg, httpServer, http3Server, health.NewChecker())
+		}()
+		waitForListener(t, cfg.Server.Addr)
+
+		// Act: discover HTTP/3 through the HTTP/2 response
+		resp, err := insecureClient().Get("https://" + cfg.Server.Addr + "/proto")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "HTTP/2.0", string(body))
+
+		altSvc := regexp.MustCompile(`^h3=":(\d+)"; ma=86400$`).FindStringSubmatch(resp.Header.Get("Alt-Svc"))
+		require.Len(t, altSvc, 2, "unexpected Alt-Svc header %q", resp.Header.Get("Alt-Svc"))
+		host, _, _ := net.SplitHostPort(cfg.Server.Addr)
+
+		// Act: switch to the advertised HTTP/3 endpoint
+		transport := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec
+		defer transport.Close()
+		h3Client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+		resp, err = h3Client.Get("https://" + net.JoinHostPort(host, altSvc[1]) + "/proto")
+
+		// Assert
+		require.NoError(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "HTTP/3.0", string(body))
+
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+
+	t.Run("should advertise the configured port and max age", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.HTTP3.Port = 443
+		cfg.HTTP3.AltSvcMaxAge = time.Hour
+
+		header, err := altSvcHeader(createHTTP3Server(cfg, http.NotFoundHandler()), cfg.HTTP3.AltSvcMaxAge)
+		require.NoError(t, err)
+		assert.Equal(t, `h3=":443"; ma=3600`, header)
+	})
+
+	t.Run("should not advertise HTTP/3 when disabled", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.HTTP3.AltSvc = false
+		mux := http.NewServeMux()
+		httpServer := createHTTP2Server(cfg, mux, createHTTP3Server(cfg, mux))
+
+		recorder := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, recorder.Header().Get("Alt-Svc"))
+	})
+}
+
+func TestStructuredLogging(t *testing.T) {
+	// Arrange
+	var out syncBuffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &out))
+	defer slog.SetDefault(previous)
+
+	cfg := testConfig(t)
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
+	handler := logging.Handler(setupMux(cfg, checker, internal.NewBasicServiceV1(cfg.Background)))
+	http3Server := createHTTP3Server(cfg, handler)
+	httpServer := createHTTP2Server(cfg, handler, http3Server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- setupListeners(ctx, cfg, httpServer, http3Server, checker)
+	}()
+	waitForListener(t, cfg.Server.Addr)
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec
+	defer transport.Close()
+
+	// callFinished returns the log record of the finished call with requestID.
+	callFinished := func(t *testing.T, requestID string) map[string]any {
+		var found map[string]any
+		require.Eventually(t, func() bool {
+			for _, line := range strings.Split(out.String(), "\n") {
+				var record map[string]any
+				if json.Unmarshal([]byte(line), &record) == nil && record["msg"] == "Call finished" && record["request_id"] == requestID {
+					found = record
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 10*time.Millisecond)
+		return found
+	}
+
+	for _, tc := range []struct {
+		name        string
+		requestID   string
+		client      *http.Client
+		option      connect.ClientOption
+		httpVersion string
+		protocol    string
+	}{
+		{"should log gRPC calls over HTTP/2", "grpc-h2", insecureClient(), connect.WithGRPC(), "h2", "grpc"},
+		{"should log Connect calls over HTTP/3", "connect-h3", &http.Client{Transport: transport}, connect.WithProtoJSON(), "h3", "connect"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			client := basicV1connect.NewBasicServiceClient(tc.client, "https://"+cfg.Server.Addr, tc.option)
+			req := connect.NewRequest(&basicServiceV1.HelloRequest{Message: "logs"})
+			req.Header().Set(logging.RequestIDHeader, tc.requestID)
+			_, err := client.Hello(context.Background(), req)
+			require.NoError(t, err)
+
+			// Assert
+			record := callFinished(t, tc.requestID)
+			assert.Equal(t, basicV1connect.BasicServiceHelloProcedure, record["procedure"])
+			assert.Equal(t, tc.protocol, record["protocol"])
+			assert.Equal(t, tc.httpVersion, record["http_version"])
+			assert.NotEmpty(t, record["peer"])
+			assert.Contains(t, record, "duration")
+		})
+	}
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestH2CHandlerWait(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should wait for hijacked connections to finish", func(t *testing.T) {
+		release := make(chan struct{})
+		h := newH2CHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}), &http2.Server{})
+		go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Eventually(t, func() bool { return h.active.Load() == 1 }, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, h.Wait(ctx), context.DeadlineExceeded)
+
+		close(release)
+		assert.NoError(t, h.Wait(context.Background()))
+	})
+}
+
+func TestSetupMuxAuthentication(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Auth.Mode = config.AuthModeAPIKey
+	cfg.Auth.APIKeysFile = filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(cfg.Auth.APIKeysFile, []byte("ci-pipeline: secret-1\n"), 0o600))
+	authenticator, err := auth.New(cfg.Auth)
+	require.NoError(t, err)
+
+	checker := health.NewChecker(basicV1connect.BasicServiceName)
+	mux := setupMux(cfg, checker, internal.NewBasicServiceV1(cfg.Background), auth.NewInterceptor(authenticator, cfg.Auth.Exempt))
+
+	// call sends an empty Connect unary request to procedure and returns the HTTP status.
+	call := func(procedure, key string) int {
+		req := httptest.NewRequest(http.MethodPost, procedure, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
 
-	// Simulate receiving SIGINT (CTRL+C) after servers are running
-	signalCh <- syscall.SIGINT
+	t.Run("should require credentials for the basic service", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, call(basicV1connect.BasicServiceHelloProcedure, ""))
+		assert.Equal(t, http.StatusOK, call(basicV1connect.BasicServiceHelloProcedure, "secret-1"))
+	})
+
+	t.Run("should exempt health checks", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call("/grpc.health.v1.Health/Check", ""))
+	})
+}
+
+func TestStartAdminServer(t *testing.T) {
+	t.Run("should serve admin endpoints on a separate address", func(t *testing.T) {
+		// Arrange
+		cfg := config.Default()
+		cfg.Admin.Addr = freeAddress(t)
+		service := internal.NewBasicServiceV1(cfg.Background)
+		service.StateManager.Start("job-1")
+
+		// Act
+		adminServer, err := startAdminServer(cfg, service, metrics.New(service.StateManager))
+		require.NoError(t, err)
+		defer adminServer.Close()
+
+		// Assert
+		resp, err := http.Get("http://" + cfg.Admin.Addr + "/state")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `"job-1"`)
+
+		resp, err = http.Get("http://" + cfg.Admin.Addr + "/debug/pprof/")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Get("http://" + cfg.Admin.Addr + "/metrics")
+		require.NoError(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `basic_background_jobs{state="STATE_PROCESS"} 1`)
+	})
+
+	t.Run("should not expose admin endpoints on the service mux", func(t *testing.T) {
+		cfg := config.Default()
+		mux := setupMux(cfg, health.NewChecker(), internal.NewBasicServiceV1(cfg.Background))
+
+		for _, path := range []string{"/debug/pprof/", "/debug/vars", "/metrics", "/config", "/state"} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusNotFound, rec.Code, path)
+		}
+	})
+
+	t.Run("should be disabled without an address", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Admin.Addr = ""
+
+		service := internal.NewBasicServiceV1(cfg.Background)
+		adminServer, err := startAdminServer(cfg, service, metrics.New(service.StateManager))
+		require.NoError(t, err)
+		assert.Nil(t, adminServer)
+	})
+
+	t.Run("should fail when the address is in use", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		cfg := config.Default()
+		cfg.Admin.Addr = l.Addr().String()
+
+		service := internal.NewBasicServiceV1(cfg.Background)
+		_, err = startAdminServer(cfg, service, metrics.New(service.StateManager))
+		assert.Error(t, err)
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitDrainTimeout, exitCode(errDrainTimeout))
+	assert.Equal(t, exitFailure, exitCode(os.ErrNotExist))
+}
+
+// testConfig returns the default configuration bound to a free loopback port
+// and using a freshly generated self-signed certificate.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
 
-	// Ensure servers shut down gracefully
-	err = eg.Wait()
-	assert.NoError(t, err)
+	cfg := config.Default()
+	cfg.Server.Addr = freeAddress(t)
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = writeTestCertificates(t)
+
+	return cfg
+}
+
+// writeTestCertificates writes a self-signed certificate valid for 127.0.0.1 and
+// localhost into a temporary directory and returns the certificate and key paths.
+func writeTestCertificates(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "local.crt"), filepath.Join(dir, "local.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+// freeAddress returns a loopback address with a port that is currently unused.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
+
+// waitForListener blocks until a TCP connection to addr succeeds.
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// insecureClient returns an HTTP/2 capable client that skips certificate verification.
+func insecureClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by loggers and tests.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
 
-	// Stop listening for OS signals
-	signal.Stop(signalCh)
-	close(signalCh)
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
 }
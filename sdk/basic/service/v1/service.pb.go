@@ -1,3 +1,8 @@
+// Basic Service Protocol Definitions
+//
+// This file defines the core data structures and enums used by the BasicService.
+// It includes state management, request/response messages, and Cloud Events integration.
+
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.36.6
@@ -7,9 +12,11 @@
 package basicServiceV1
 
 import (
+	_ "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	v1 "github.com/soundphilosopher/basic-grpc-service-go/sdk/io/cloudevents/v1"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -23,14 +30,16 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// State represents the lifecycle state of background operations.
 type State int32
 
 const (
-	State_STATE_UNSPECIFIED         State = 0
-	State_STATE_PROCESS             State = 1
-	State_STATE_COMPLETE            State = 2
-	State_STATE_ERROR               State = 3
-	State_STATE_COMPLETE_WITH_ERROR State = 4
+	State_STATE_UNSPECIFIED         State = 0 // Default unspecified state
+	State_STATE_PROCESS             State = 1 // Operation is currently processing
+	State_STATE_COMPLETE            State = 2 // Operation completed successfully
+	State_STATE_ERROR               State = 3 // Operation failed with error
+	State_STATE_COMPLETE_WITH_ERROR State = 4 // Operation completed but with some errors
+	State_STATE_CANCELLED           State = 5 // Operation was cancelled before it completed
 )
 
 // Enum value maps for State.
@@ -41,6 +50,7 @@ var (
 		2: "STATE_COMPLETE",
 		3: "STATE_ERROR",
 		4: "STATE_COMPLETE_WITH_ERROR",
+		5: "STATE_CANCELLED",
 	}
 	State_value = map[string]int32{
 		"STATE_UNSPECIFIED":         0,
@@ -48,6 +58,7 @@ var (
 		"STATE_COMPLETE":            2,
 		"STATE_ERROR":               3,
 		"STATE_COMPLETE_WITH_ERROR": 4,
+		"STATE_CANCELLED":           5,
 	}
 )
 
@@ -78,10 +89,11 @@ func (State) EnumDescriptor() ([]byte, []int) {
 	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{0}
 }
 
+// SomeServiceData contains the payload data from external service calls.
 type SomeServiceData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
-	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"` // The actual data value returned by the service
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`   // The type of service that provided this data (rest, rpc, grpc)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -130,12 +142,13 @@ func (x *SomeServiceData) GetType() string {
 	return ""
 }
 
+// SomeServiceResponse represents a response from an external service call.
 type SomeServiceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
-	Data          *SomeServiceData       `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`           // Unique identifier for this response
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`       // Name of the service that provided the response
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"` // Version of the service
+	Data          *SomeServiceData       `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`       // The actual response data
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -198,16 +211,94 @@ func (x *SomeServiceResponse) GetData() *SomeServiceData {
 	return nil
 }
 
+// ProcessResult reports the downstream call made by one process of a background operation.
+type ProcessResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Process       int64                  `protobuf:"varint,1,opt,name=process,proto3" json:"process,omitempty"`                         // Index of the process, from 0 to the number of processes - 1
+	Service       string                 `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`                          // Name of the called service in the service registry
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`                                // Type of the called service (rest, rpc, grpc)
+	State         State                  `protobuf:"varint,4,opt,name=state,proto3,enum=basic.service.v1.State" json:"state,omitempty"` // STATE_PROCESS while the call runs, then STATE_COMPLETE, STATE_ERROR, or STATE_CANCELLED if the job was cancelled first
+	Responses     []*SomeServiceResponse `protobuf:"bytes,5,rep,name=responses,proto3" json:"responses,omitempty"`                      // Responses returned by the service
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessResult) Reset() {
+	*x = ProcessResult{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessResult) ProtoMessage() {}
+
+func (x *ProcessResult) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessResult.ProtoReflect.Descriptor instead.
+func (*ProcessResult) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ProcessResult) GetProcess() int64 {
+	if x != nil {
+		return x.Process
+	}
+	return 0
+}
+
+func (x *ProcessResult) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ProcessResult) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ProcessResult) GetState() State {
+	if x != nil {
+		return x.State
+	}
+	return State_STATE_UNSPECIFIED
+}
+
+func (x *ProcessResult) GetResponses() []*SomeServiceResponse {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+// SomeServiceResponses is a collection of service responses.
 type SomeServiceResponses struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Responses     []*SomeServiceResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	Responses     []*SomeServiceResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"` // List of individual service responses
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SomeServiceResponses) Reset() {
 	*x = SomeServiceResponses{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[2]
+	mi := &file_basic_service_v1_service_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -219,7 +310,7 @@ func (x *SomeServiceResponses) String() string {
 func (*SomeServiceResponses) ProtoMessage() {}
 
 func (x *SomeServiceResponses) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[2]
+	mi := &file_basic_service_v1_service_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -232,7 +323,7 @@ func (x *SomeServiceResponses) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SomeServiceResponses.ProtoReflect.Descriptor instead.
 func (*SomeServiceResponses) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{2}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *SomeServiceResponses) GetResponses() []*SomeServiceResponse {
@@ -242,16 +333,17 @@ func (x *SomeServiceResponses) GetResponses() []*SomeServiceResponse {
 	return nil
 }
 
+// HelloRequest contains the greeting message to be processed.
 type HelloRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"` // The message to include in the greeting
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HelloRequest) Reset() {
 	*x = HelloRequest{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[3]
+	mi := &file_basic_service_v1_service_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -263,7 +355,7 @@ func (x *HelloRequest) String() string {
 func (*HelloRequest) ProtoMessage() {}
 
 func (x *HelloRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[3]
+	mi := &file_basic_service_v1_service_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -276,7 +368,7 @@ func (x *HelloRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HelloRequest.ProtoReflect.Descriptor instead.
 func (*HelloRequest) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{3}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *HelloRequest) GetMessage() string {
@@ -286,16 +378,17 @@ func (x *HelloRequest) GetMessage() string {
 	return ""
 }
 
+// HelloResponse wraps the greeting response in a Cloud Event.
 type HelloResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	CloudEvent    *v1.CloudEvent         `protobuf:"bytes,1,opt,name=cloud_event,json=cloudEvent,proto3" json:"cloud_event,omitempty"`
+	CloudEvent    *v1.CloudEvent         `protobuf:"bytes,1,opt,name=cloud_event,json=cloudEvent,proto3" json:"cloud_event,omitempty"` // Cloud Event containing the greeting
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HelloResponse) Reset() {
 	*x = HelloResponse{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[4]
+	mi := &file_basic_service_v1_service_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -307,7 +400,7 @@ func (x *HelloResponse) String() string {
 func (*HelloResponse) ProtoMessage() {}
 
 func (x *HelloResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[4]
+	mi := &file_basic_service_v1_service_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -320,7 +413,7 @@ func (x *HelloResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HelloResponse.ProtoReflect.Descriptor instead.
 func (*HelloResponse) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{4}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *HelloResponse) GetCloudEvent() *v1.CloudEvent {
@@ -330,16 +423,17 @@ func (x *HelloResponse) GetCloudEvent() *v1.CloudEvent {
 	return nil
 }
 
+// HelloResponseEvent is the actual event data for hello responses.
 type HelloResponseEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Greeting      string                 `protobuf:"bytes,1,opt,name=greeting,proto3" json:"greeting,omitempty"`
+	Greeting      string                 `protobuf:"bytes,1,opt,name=greeting,proto3" json:"greeting,omitempty"` // The formatted greeting message
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HelloResponseEvent) Reset() {
 	*x = HelloResponseEvent{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[5]
+	mi := &file_basic_service_v1_service_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -351,7 +445,7 @@ func (x *HelloResponseEvent) String() string {
 func (*HelloResponseEvent) ProtoMessage() {}
 
 func (x *HelloResponseEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[5]
+	mi := &file_basic_service_v1_service_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,7 +458,7 @@ func (x *HelloResponseEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HelloResponseEvent.ProtoReflect.Descriptor instead.
 func (*HelloResponseEvent) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{5}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *HelloResponseEvent) GetGreeting() string {
@@ -374,16 +468,17 @@ func (x *HelloResponseEvent) GetGreeting() string {
 	return ""
 }
 
+// TalkRequest contains a message for the conversational interface.
 type TalkRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"` // User input message for the chat bot
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TalkRequest) Reset() {
 	*x = TalkRequest{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[6]
+	mi := &file_basic_service_v1_service_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -395,7 +490,7 @@ func (x *TalkRequest) String() string {
 func (*TalkRequest) ProtoMessage() {}
 
 func (x *TalkRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[6]
+	mi := &file_basic_service_v1_service_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -408,7 +503,7 @@ func (x *TalkRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TalkRequest.ProtoReflect.Descriptor instead.
 func (*TalkRequest) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{6}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *TalkRequest) GetMessage() string {
@@ -418,16 +513,17 @@ func (x *TalkRequest) GetMessage() string {
 	return ""
 }
 
+// TalkResponse contains the chat bot's reply.
 type TalkResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Answer        string                 `protobuf:"bytes,1,opt,name=answer,proto3" json:"answer,omitempty"`
+	Answer        string                 `protobuf:"bytes,1,opt,name=answer,proto3" json:"answer,omitempty"` // The chat bot's response to the user input
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TalkResponse) Reset() {
 	*x = TalkResponse{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[7]
+	mi := &file_basic_service_v1_service_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -439,7 +535,7 @@ func (x *TalkResponse) String() string {
 func (*TalkResponse) ProtoMessage() {}
 
 func (x *TalkResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[7]
+	mi := &file_basic_service_v1_service_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -452,7 +548,7 @@ func (x *TalkResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TalkResponse.ProtoReflect.Descriptor instead.
 func (*TalkResponse) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{7}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *TalkResponse) GetAnswer() string {
@@ -462,16 +558,19 @@ func (x *TalkResponse) GetAnswer() string {
 	return ""
 }
 
+// BackgroundRequest initiates a background processing operation.
 type BackgroundRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Processes     int64                  `protobuf:"varint,1,opt,name=processes,proto3" json:"processes,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Processes         int64                  `protobuf:"varint,1,opt,name=processes,proto3" json:"processes,omitempty"`                                         // Number of downstream calls to make, 0 calls every registered service once. Ignored when reattaching
+	JobId             string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`                                     // ID of an existing job to reattach to, a new job is started if empty
+	HeartbeatInterval *durationpb.Duration   `protobuf:"bytes,3,opt,name=heartbeat_interval,json=heartbeatInterval,proto3" json:"heartbeat_interval,omitempty"` // Interval at which the unchanged state is sent again while nothing changes, the server default if unset, 0 disables heartbeats
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *BackgroundRequest) Reset() {
 	*x = BackgroundRequest{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[8]
+	mi := &file_basic_service_v1_service_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -483,7 +582,7 @@ func (x *BackgroundRequest) String() string {
 func (*BackgroundRequest) ProtoMessage() {}
 
 func (x *BackgroundRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[8]
+	mi := &file_basic_service_v1_service_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -496,7 +595,7 @@ func (x *BackgroundRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackgroundRequest.ProtoReflect.Descriptor instead.
 func (*BackgroundRequest) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{8}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *BackgroundRequest) GetProcesses() int64 {
@@ -506,16 +605,31 @@ func (x *BackgroundRequest) GetProcesses() int64 {
 	return 0
 }
 
+func (x *BackgroundRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *BackgroundRequest) GetHeartbeatInterval() *durationpb.Duration {
+	if x != nil {
+		return x.HeartbeatInterval
+	}
+	return nil
+}
+
+// BackgroundResponse provides status updates for background operations.
 type BackgroundResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	CloudEvent    *v1.CloudEvent         `protobuf:"bytes,1,opt,name=cloud_event,json=cloudEvent,proto3" json:"cloud_event,omitempty"`
+	CloudEvent    *v1.CloudEvent         `protobuf:"bytes,1,opt,name=cloud_event,json=cloudEvent,proto3" json:"cloud_event,omitempty"` // Cloud Event containing the status update
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BackgroundResponse) Reset() {
 	*x = BackgroundResponse{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[9]
+	mi := &file_basic_service_v1_service_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -527,7 +641,7 @@ func (x *BackgroundResponse) String() string {
 func (*BackgroundResponse) ProtoMessage() {}
 
 func (x *BackgroundResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[9]
+	mi := &file_basic_service_v1_service_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -540,7 +654,7 @@ func (x *BackgroundResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackgroundResponse.ProtoReflect.Descriptor instead.
 func (*BackgroundResponse) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{9}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *BackgroundResponse) GetCloudEvent() *v1.CloudEvent {
@@ -550,19 +664,22 @@ func (x *BackgroundResponse) GetCloudEvent() *v1.CloudEvent {
 	return nil
 }
 
+// BackgroundResponseEvent contains the actual status data for background operations.
 type BackgroundResponseEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	State         State                  `protobuf:"varint,1,opt,name=state,proto3,enum=basic.service.v1.State" json:"state,omitempty"`
-	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
-	CompletedAt   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
-	Responses     []*SomeServiceResponse `protobuf:"bytes,4,rep,name=responses,proto3" json:"responses,omitempty"`
+	State         State                  `protobuf:"varint,1,opt,name=state,proto3,enum=basic.service.v1.State" json:"state,omitempty"`   // Current state of the operation
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`       // When the operation started
+	CompletedAt   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"` // When the operation completed (if finished)
+	Responses     []*SomeServiceResponse `protobuf:"bytes,4,rep,name=responses,proto3" json:"responses,omitempty"`                        // Collected responses from external services
+	Results       []*ProcessResult       `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`                            // Result of every process, ordered by process index
+	JobId         string                 `protobuf:"bytes,6,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`                   // ID of the job, pass it as BackgroundRequest.job_id to reattach after a dropped connection
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BackgroundResponseEvent) Reset() {
 	*x = BackgroundResponseEvent{}
-	mi := &file_basic_service_v1_service_proto_msgTypes[10]
+	mi := &file_basic_service_v1_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -574,7 +691,7 @@ func (x *BackgroundResponseEvent) String() string {
 func (*BackgroundResponseEvent) ProtoMessage() {}
 
 func (x *BackgroundResponseEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_basic_service_v1_service_proto_msgTypes[10]
+	mi := &file_basic_service_v1_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -587,7 +704,7 @@ func (x *BackgroundResponseEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackgroundResponseEvent.ProtoReflect.Descriptor instead.
 func (*BackgroundResponseEvent) Descriptor() ([]byte, []int) {
-	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{10}
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *BackgroundResponseEvent) GetState() State {
@@ -618,11 +735,404 @@ func (x *BackgroundResponseEvent) GetResponses() []*SomeServiceResponse {
 	return nil
 }
 
+func (x *BackgroundResponseEvent) GetResults() []*ProcessResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *BackgroundResponseEvent) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// BackgroundJob describes a background operation started by Background.
+type BackgroundJob struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`                   // ID of the job
+	State         State                  `protobuf:"varint,2,opt,name=state,proto3,enum=basic.service.v1.State" json:"state,omitempty"`   // Current state of the job
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`       // When the job started
+	CompletedAt   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"` // When the job completed or was cancelled (if finished)
+	Results       []*ProcessResult       `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`                            // Result of every process, ordered by process index
+	Errors        []string               `protobuf:"bytes,6,rep,name=errors,proto3" json:"errors,omitempty"`                              // Errors of failed processes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackgroundJob) Reset() {
+	*x = BackgroundJob{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackgroundJob) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackgroundJob) ProtoMessage() {}
+
+func (x *BackgroundJob) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackgroundJob.ProtoReflect.Descriptor instead.
+func (*BackgroundJob) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BackgroundJob) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *BackgroundJob) GetState() State {
+	if x != nil {
+		return x.State
+	}
+	return State_STATE_UNSPECIFIED
+}
+
+func (x *BackgroundJob) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *BackgroundJob) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *BackgroundJob) GetResults() []*ProcessResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *BackgroundJob) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+// ListBackgroundJobsRequest selects a page of the background jobs of the caller.
+type ListBackgroundJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	States        []State                `protobuf:"varint,1,rep,packed,name=states,proto3,enum=basic.service.v1.State" json:"states,omitempty"` // Only list jobs in one of these states, all jobs if empty
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`                // Maximum number of jobs to return, 0 uses the default of 50
+	PageToken     string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`              // next_page_token of the previous page, empty for the first page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBackgroundJobsRequest) Reset() {
+	*x = ListBackgroundJobsRequest{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBackgroundJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBackgroundJobsRequest) ProtoMessage() {}
+
+func (x *ListBackgroundJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBackgroundJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListBackgroundJobsRequest) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListBackgroundJobsRequest) GetStates() []State {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ListBackgroundJobsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListBackgroundJobsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+// ListBackgroundJobsResponse is a page of background jobs ordered by start time.
+type ListBackgroundJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*BackgroundJob       `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`                                          // Jobs of the page
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Token of the next page, empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBackgroundJobsResponse) Reset() {
+	*x = ListBackgroundJobsResponse{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBackgroundJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBackgroundJobsResponse) ProtoMessage() {}
+
+func (x *ListBackgroundJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBackgroundJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListBackgroundJobsResponse) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListBackgroundJobsResponse) GetJobs() []*BackgroundJob {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListBackgroundJobsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// GetBackgroundJobRequest identifies the background job to return.
+type GetBackgroundJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"` // ID of the job
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBackgroundJobRequest) Reset() {
+	*x = GetBackgroundJobRequest{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBackgroundJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBackgroundJobRequest) ProtoMessage() {}
+
+func (x *GetBackgroundJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBackgroundJobRequest.ProtoReflect.Descriptor instead.
+func (*GetBackgroundJobRequest) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetBackgroundJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// GetBackgroundJobResponse contains the requested background job.
+type GetBackgroundJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Job           *BackgroundJob         `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"` // The job
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBackgroundJobResponse) Reset() {
+	*x = GetBackgroundJobResponse{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBackgroundJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBackgroundJobResponse) ProtoMessage() {}
+
+func (x *GetBackgroundJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBackgroundJobResponse.ProtoReflect.Descriptor instead.
+func (*GetBackgroundJobResponse) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetBackgroundJobResponse) GetJob() *BackgroundJob {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+// CancelBackgroundJobRequest identifies the background job to cancel.
+type CancelBackgroundJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"` // ID of the job
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelBackgroundJobRequest) Reset() {
+	*x = CancelBackgroundJobRequest{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelBackgroundJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBackgroundJobRequest) ProtoMessage() {}
+
+func (x *CancelBackgroundJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBackgroundJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelBackgroundJobRequest) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CancelBackgroundJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// CancelBackgroundJobResponse contains the background job after it was cancelled.
+type CancelBackgroundJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Job           *BackgroundJob         `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"` // The job in STATE_CANCELLED
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelBackgroundJobResponse) Reset() {
+	*x = CancelBackgroundJobResponse{}
+	mi := &file_basic_service_v1_service_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelBackgroundJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBackgroundJobResponse) ProtoMessage() {}
+
+func (x *CancelBackgroundJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_basic_service_v1_service_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBackgroundJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelBackgroundJobResponse) Descriptor() ([]byte, []int) {
+	return file_basic_service_v1_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CancelBackgroundJobResponse) GetJob() *BackgroundJob {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
 var File_basic_service_v1_service_proto protoreflect.FileDescriptor
 
 const file_basic_service_v1_service_proto_rawDesc = "" +
 	"\n" +
-	"\x1ebasic/service/v1/service.proto\x12\x10basic.service.v1\x1a#io/cloudevents/v1/cloudevents.proto\x1a\x1fgoogle/protobuf/timestamp.proto\";\n" +
+	"\x1ebasic/service/v1/service.proto\x12\x10basic.service.v1\x1a\x1bbuf/validate/validate.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a#io/cloudevents/v1/cloudevents.proto\";\n" +
 	"\x0fSomeServiceData\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\tR\x05value\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\"\x8a\x01\n" +
@@ -630,37 +1140,75 @@ const file_basic_service_v1_service_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
 	"\aversion\x18\x03 \x01(\tR\aversion\x125\n" +
-	"\x04data\x18\x04 \x01(\v2!.basic.service.v1.SomeServiceDataR\x04data\"[\n" +
+	"\x04data\x18\x04 \x01(\v2!.basic.service.v1.SomeServiceDataR\x04data\"\xcb\x01\n" +
+	"\rProcessResult\x12\x18\n" +
+	"\aprocess\x18\x01 \x01(\x03R\aprocess\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12-\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x17.basic.service.v1.StateR\x05state\x12C\n" +
+	"\tresponses\x18\x05 \x03(\v2%.basic.service.v1.SomeServiceResponseR\tresponses\"[\n" +
 	"\x14SomeServiceResponses\x12C\n" +
-	"\tresponses\x18\x01 \x03(\v2%.basic.service.v1.SomeServiceResponseR\tresponses\"(\n" +
-	"\fHelloRequest\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"O\n" +
+	"\tresponses\x18\x01 \x03(\v2%.basic.service.v1.SomeServiceResponseR\tresponses\"4\n" +
+	"\fHelloRequest\x12$\n" +
+	"\amessage\x18\x01 \x01(\tB\n" +
+	"\xbaH\ar\x05\x10\x01\x18\x80\x02R\amessage\"O\n" +
 	"\rHelloResponse\x12>\n" +
 	"\vcloud_event\x18\x01 \x01(\v2\x1d.io.cloudevents.v1.CloudEventR\n" +
 	"cloudEvent\"0\n" +
 	"\x12HelloResponseEvent\x12\x1a\n" +
-	"\bgreeting\x18\x01 \x01(\tR\bgreeting\"'\n" +
-	"\vTalkRequest\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"&\n" +
+	"\bgreeting\x18\x01 \x01(\tR\bgreeting\"1\n" +
+	"\vTalkRequest\x12\"\n" +
+	"\amessage\x18\x01 \x01(\tB\b\xbaH\x05r\x03\x18\x80\bR\amessage\"&\n" +
 	"\fTalkResponse\x12\x16\n" +
-	"\x06answer\x18\x01 \x01(\tR\x06answer\"1\n" +
-	"\x11BackgroundRequest\x12\x1c\n" +
-	"\tprocesses\x18\x01 \x01(\x03R\tprocesses\"T\n" +
+	"\x06answer\x18\x01 \x01(\tR\x06answer\"\xab\x02\n" +
+	"\x11BackgroundRequest\x12'\n" +
+	"\tprocesses\x18\x01 \x01(\x03B\t\xbaH\x06\"\x04\x18d(\x00R\tprocesses\x12\"\n" +
+	"\x06job_id\x18\x02 \x01(\tB\v\xbaH\b\xd8\x01\x01r\x03\xb0\x01\x01R\x05jobId\x12\xc8\x01\n" +
+	"\x12heartbeat_interval\x18\x03 \x01(\v2\x19.google.protobuf.DurationB~\xbaH{\xba\x01p\n" +
+	"\x16heartbeat_interval.min\x12!value must be 0 or at least 100ms\x1a3this == duration('0s') || this >= duration('100ms')\xaa\x01\x05\"\x03\b\x90\x1cR\x11heartbeatInterval\"T\n" +
 	"\x12BackgroundResponse\x12>\n" +
 	"\vcloud_event\x18\x01 \x01(\v2\x1d.io.cloudevents.v1.CloudEventR\n" +
-	"cloudEvent\"\x87\x02\n" +
+	"cloudEvent\"\xd9\x02\n" +
 	"\x17BackgroundResponseEvent\x12-\n" +
 	"\x05state\x18\x01 \x01(\x0e2\x17.basic.service.v1.StateR\x05state\x129\n" +
 	"\n" +
 	"started_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
 	"\fcompleted_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x12C\n" +
-	"\tresponses\x18\x04 \x03(\v2%.basic.service.v1.SomeServiceResponseR\tresponses*u\n" +
+	"\tresponses\x18\x04 \x03(\v2%.basic.service.v1.SomeServiceResponseR\tresponses\x129\n" +
+	"\aresults\x18\x05 \x03(\v2\x1f.basic.service.v1.ProcessResultR\aresults\x12\x15\n" +
+	"\x06job_id\x18\x06 \x01(\tR\x05jobId\"\xa2\x02\n" +
+	"\rBackgroundJob\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12-\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x17.basic.service.v1.StateR\x05state\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x129\n" +
+	"\aresults\x18\x05 \x03(\v2\x1f.basic.service.v1.ProcessResultR\aresults\x12\x16\n" +
+	"\x06errors\x18\x06 \x03(\tR\x06errors\"\xa5\x01\n" +
+	"\x19ListBackgroundJobsRequest\x12@\n" +
+	"\x06states\x18\x01 \x03(\x0e2\x17.basic.service.v1.StateB\x0f\xbaH\f\x92\x01\t\"\a\x82\x01\x04\x10\x01 \x00R\x06states\x12'\n" +
+	"\tpage_size\x18\x02 \x01(\x05B\n" +
+	"\xbaH\a\x1a\x05\x18\xe8\a(\x00R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"y\n" +
+	"\x1aListBackgroundJobsResponse\x123\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x1f.basic.service.v1.BackgroundJobR\x04jobs\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\":\n" +
+	"\x17GetBackgroundJobRequest\x12\x1f\n" +
+	"\x06job_id\x18\x01 \x01(\tB\b\xbaH\x05r\x03\xb0\x01\x01R\x05jobId\"M\n" +
+	"\x18GetBackgroundJobResponse\x121\n" +
+	"\x03job\x18\x01 \x01(\v2\x1f.basic.service.v1.BackgroundJobR\x03job\"=\n" +
+	"\x1aCancelBackgroundJobRequest\x12\x1f\n" +
+	"\x06job_id\x18\x01 \x01(\tB\b\xbaH\x05r\x03\xb0\x01\x01R\x05jobId\"P\n" +
+	"\x1bCancelBackgroundJobResponse\x121\n" +
+	"\x03job\x18\x01 \x01(\v2\x1f.basic.service.v1.BackgroundJobR\x03job*\x8a\x01\n" +
 	"\x05State\x12\x15\n" +
 	"\x11STATE_UNSPECIFIED\x10\x00\x12\x11\n" +
 	"\rSTATE_PROCESS\x10\x01\x12\x12\n" +
 	"\x0eSTATE_COMPLETE\x10\x02\x12\x0f\n" +
 	"\vSTATE_ERROR\x10\x03\x12\x1d\n" +
-	"\x19STATE_COMPLETE_WITH_ERROR\x10\x04BWZUgithub.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1;basicServiceV1b\x06proto3"
+	"\x19STATE_COMPLETE_WITH_ERROR\x10\x04\x12\x13\n" +
+	"\x0fSTATE_CANCELLED\x10\x05BWZUgithub.com/soundphilosopher/basic-grpc-service-go/sdk/basic/service/v1;basicServiceV1b\x06proto3"
 
 var (
 	file_basic_service_v1_service_proto_rawDescOnce sync.Once
@@ -675,37 +1223,58 @@ func file_basic_service_v1_service_proto_rawDescGZIP() []byte {
 }
 
 var file_basic_service_v1_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_basic_service_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_basic_service_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_basic_service_v1_service_proto_goTypes = []any{
-	(State)(0),                      // 0: basic.service.v1.State
-	(*SomeServiceData)(nil),         // 1: basic.service.v1.SomeServiceData
-	(*SomeServiceResponse)(nil),     // 2: basic.service.v1.SomeServiceResponse
-	(*SomeServiceResponses)(nil),    // 3: basic.service.v1.SomeServiceResponses
-	(*HelloRequest)(nil),            // 4: basic.service.v1.HelloRequest
-	(*HelloResponse)(nil),           // 5: basic.service.v1.HelloResponse
-	(*HelloResponseEvent)(nil),      // 6: basic.service.v1.HelloResponseEvent
-	(*TalkRequest)(nil),             // 7: basic.service.v1.TalkRequest
-	(*TalkResponse)(nil),            // 8: basic.service.v1.TalkResponse
-	(*BackgroundRequest)(nil),       // 9: basic.service.v1.BackgroundRequest
-	(*BackgroundResponse)(nil),      // 10: basic.service.v1.BackgroundResponse
-	(*BackgroundResponseEvent)(nil), // 11: basic.service.v1.BackgroundResponseEvent
-	(*v1.CloudEvent)(nil),           // 12: io.cloudevents.v1.CloudEvent
-	(*timestamppb.Timestamp)(nil),   // 13: google.protobuf.Timestamp
+	(State)(0),                          // 0: basic.service.v1.State
+	(*SomeServiceData)(nil),             // 1: basic.service.v1.SomeServiceData
+	(*SomeServiceResponse)(nil),         // 2: basic.service.v1.SomeServiceResponse
+	(*ProcessResult)(nil),               // 3: basic.service.v1.ProcessResult
+	(*SomeServiceResponses)(nil),        // 4: basic.service.v1.SomeServiceResponses
+	(*HelloRequest)(nil),                // 5: basic.service.v1.HelloRequest
+	(*HelloResponse)(nil),               // 6: basic.service.v1.HelloResponse
+	(*HelloResponseEvent)(nil),          // 7: basic.service.v1.HelloResponseEvent
+	(*TalkRequest)(nil),                 // 8: basic.service.v1.TalkRequest
+	(*TalkResponse)(nil),                // 9: basic.service.v1.TalkResponse
+	(*BackgroundRequest)(nil),           // 10: basic.service.v1.BackgroundRequest
+	(*BackgroundResponse)(nil),          // 11: basic.service.v1.BackgroundResponse
+	(*BackgroundResponseEvent)(nil),     // 12: basic.service.v1.BackgroundResponseEvent
+	(*BackgroundJob)(nil),               // 13: basic.service.v1.BackgroundJob
+	(*ListBackgroundJobsRequest)(nil),   // 14: basic.service.v1.ListBackgroundJobsRequest
+	(*ListBackgroundJobsResponse)(nil),  // 15: basic.service.v1.ListBackgroundJobsResponse
+	(*GetBackgroundJobRequest)(nil),     // 16: basic.service.v1.GetBackgroundJobRequest
+	(*GetBackgroundJobResponse)(nil),    // 17: basic.service.v1.GetBackgroundJobResponse
+	(*CancelBackgroundJobRequest)(nil),  // 18: basic.service.v1.CancelBackgroundJobRequest
+	(*CancelBackgroundJobResponse)(nil), // 19: basic.service.v1.CancelBackgroundJobResponse
+	(*v1.CloudEvent)(nil),               // 20: io.cloudevents.v1.CloudEvent
+	(*durationpb.Duration)(nil),         // 21: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),       // 22: google.protobuf.Timestamp
 }
 var file_basic_service_v1_service_proto_depIdxs = []int32{
 	1,  // 0: basic.service.v1.SomeServiceResponse.data:type_name -> basic.service.v1.SomeServiceData
-	2,  // 1: basic.service.v1.SomeServiceResponses.responses:type_name -> basic.service.v1.SomeServiceResponse
-	12, // 2: basic.service.v1.HelloResponse.cloud_event:type_name -> io.cloudevents.v1.CloudEvent
-	12, // 3: basic.service.v1.BackgroundResponse.cloud_event:type_name -> io.cloudevents.v1.CloudEvent
-	0,  // 4: basic.service.v1.BackgroundResponseEvent.state:type_name -> basic.service.v1.State
-	13, // 5: basic.service.v1.BackgroundResponseEvent.started_at:type_name -> google.protobuf.Timestamp
-	13, // 6: basic.service.v1.BackgroundResponseEvent.completed_at:type_name -> google.protobuf.Timestamp
-	2,  // 7: basic.service.v1.BackgroundResponseEvent.responses:type_name -> basic.service.v1.SomeServiceResponse
-	8,  // [8:8] is the sub-list for method output_type
-	8,  // [8:8] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	0,  // 1: basic.service.v1.ProcessResult.state:type_name -> basic.service.v1.State
+	2,  // 2: basic.service.v1.ProcessResult.responses:type_name -> basic.service.v1.SomeServiceResponse
+	2,  // 3: basic.service.v1.SomeServiceResponses.responses:type_name -> basic.service.v1.SomeServiceResponse
+	20, // 4: basic.service.v1.HelloResponse.cloud_event:type_name -> io.cloudevents.v1.CloudEvent
+	21, // 5: basic.service.v1.BackgroundRequest.heartbeat_interval:type_name -> google.protobuf.Duration
+	20, // 6: basic.service.v1.BackgroundResponse.cloud_event:type_name -> io.cloudevents.v1.CloudEvent
+	0,  // 7: basic.service.v1.BackgroundResponseEvent.state:type_name -> basic.service.v1.State
+	22, // 8: basic.service.v1.BackgroundResponseEvent.started_at:type_name -> google.protobuf.Timestamp
+	22, // 9: basic.service.v1.BackgroundResponseEvent.completed_at:type_name -> google.protobuf.Timestamp
+	2,  // 10: basic.service.v1.BackgroundResponseEvent.responses:type_name -> basic.service.v1.SomeServiceResponse
+	3,  // 11: basic.service.v1.BackgroundResponseEvent.results:type_name -> basic.service.v1.ProcessResult
+	0,  // 12: basic.service.v1.BackgroundJob.state:type_name -> basic.service.v1.State
+	22, // 13: basic.service.v1.BackgroundJob.started_at:type_name -> google.protobuf.Timestamp
+	22, // 14: basic.service.v1.BackgroundJob.completed_at:type_name -> google.protobuf.Timestamp
+	3,  // 15: basic.service.v1.BackgroundJob.results:type_name -> basic.service.v1.ProcessResult
+	0,  // 16: basic.service.v1.ListBackgroundJobsRequest.states:type_name -> basic.service.v1.State
+	13, // 17: basic.service.v1.ListBackgroundJobsResponse.jobs:type_name -> basic.service.v1.BackgroundJob
+	13, // 18: basic.service.v1.GetBackgroundJobResponse.job:type_name -> basic.service.v1.BackgroundJob
+	13, // 19: basic.service.v1.CancelBackgroundJobResponse.job:type_name -> basic.service.v1.BackgroundJob
+	20, // [20:20] is the sub-list for method output_type
+	20, // [20:20] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_basic_service_v1_service_proto_init() }
@@ -719,7 +1288,7 @@ func file_basic_service_v1_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_basic_service_v1_service_proto_rawDesc), len(file_basic_service_v1_service_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   11,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
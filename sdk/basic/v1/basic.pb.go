@@ -1,3 +1,8 @@
+// BasicService gRPC API Definition
+//
+// This file defines the main gRPC service interface for the Basic Service,
+// providing simple greeting, conversational, and background processing capabilities.
+
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.36.6
@@ -25,33 +30,48 @@ var File_basic_v1_basic_proto protoreflect.FileDescriptor
 
 const file_basic_v1_basic_proto_rawDesc = "" +
 	"\n" +
-	"\x14basic/v1/basic.proto\x12\bbasic.v1\x1a\x1ebasic/service/v1/service.proto2\x84\x02\n" +
+	"\x14basic/v1/basic.proto\x12\bbasic.v1\x1a\x1ebasic/service/v1/service.proto2\xe3\x04\n" +
 	"\fBasicService\x12J\n" +
 	"\x05Hello\x12\x1e.basic.service.v1.HelloRequest\x1a\x1f.basic.service.v1.HelloResponse\"\x00\x12K\n" +
 	"\x04Talk\x12\x1d.basic.service.v1.TalkRequest\x1a\x1e.basic.service.v1.TalkResponse\"\x00(\x010\x01\x12[\n" +
 	"\n" +
-	"Background\x12#.basic.service.v1.BackgroundRequest\x1a$.basic.service.v1.BackgroundResponse\"\x000\x01BHZFgithub.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1;basicV1b\x06proto3"
+	"Background\x12#.basic.service.v1.BackgroundRequest\x1a$.basic.service.v1.BackgroundResponse\"\x000\x01\x12t\n" +
+	"\x12ListBackgroundJobs\x12+.basic.service.v1.ListBackgroundJobsRequest\x1a,.basic.service.v1.ListBackgroundJobsResponse\"\x03\x90\x02\x01\x12n\n" +
+	"\x10GetBackgroundJob\x12).basic.service.v1.GetBackgroundJobRequest\x1a*.basic.service.v1.GetBackgroundJobResponse\"\x03\x90\x02\x01\x12w\n" +
+	"\x13CancelBackgroundJob\x12,.basic.service.v1.CancelBackgroundJobRequest\x1a-.basic.service.v1.CancelBackgroundJobResponse\"\x03\x90\x02\x02BHZFgithub.com/soundphilosopher/basic-grpc-service-go/sdk/basic/v1;basicV1b\x06proto3"
 
 var file_basic_v1_basic_proto_goTypes = []any{
-	(*v1.HelloRequest)(nil),       // 0: basic.service.v1.HelloRequest
-	(*v1.TalkRequest)(nil),        // 1: basic.service.v1.TalkRequest
-	(*v1.BackgroundRequest)(nil),  // 2: basic.service.v1.BackgroundRequest
-	(*v1.HelloResponse)(nil),      // 3: basic.service.v1.HelloResponse
-	(*v1.TalkResponse)(nil),       // 4: basic.service.v1.TalkResponse
-	(*v1.BackgroundResponse)(nil), // 5: basic.service.v1.BackgroundResponse
+	(*v1.HelloRequest)(nil),                // 0: basic.service.v1.HelloRequest
+	(*v1.TalkRequest)(nil),                 // 1: basic.service.v1.TalkRequest
+	(*v1.BackgroundRequest)(nil),           // 2: basic.service.v1.BackgroundRequest
+	(*v1.ListBackgroundJobsRequest)(nil),   // 3: basic.service.v1.ListBackgroundJobsRequest
+	(*v1.GetBackgroundJobRequest)(nil),     // 4: basic.service.v1.GetBackgroundJobRequest
+	(*v1.CancelBackgroundJobRequest)(nil),  // 5: basic.service.v1.CancelBackgroundJobRequest
+	(*v1.HelloResponse)(nil),               // 6: basic.service.v1.HelloResponse
+	(*v1.TalkResponse)(nil),                // 7: basic.service.v1.TalkResponse
+	(*v1.BackgroundResponse)(nil),          // 8: basic.service.v1.BackgroundResponse
+	(*v1.ListBackgroundJobsResponse)(nil),  // 9: basic.service.v1.ListBackgroundJobsResponse
+	(*v1.GetBackgroundJobResponse)(nil),    // 10: basic.service.v1.GetBackgroundJobResponse
+	(*v1.CancelBackgroundJobResponse)(nil), // 11: basic.service.v1.CancelBackgroundJobResponse
 }
 var file_basic_v1_basic_proto_depIdxs = []int32{
-	0, // 0: basic.v1.BasicService.Hello:input_type -> basic.service.v1.HelloRequest
-	1, // 1: basic.v1.BasicService.Talk:input_type -> basic.service.v1.TalkRequest
-	2, // 2: basic.v1.BasicService.Background:input_type -> basic.service.v1.BackgroundRequest
-	3, // 3: basic.v1.BasicService.Hello:output_type -> basic.service.v1.HelloResponse
-	4, // 4: basic.v1.BasicService.Talk:output_type -> basic.service.v1.TalkResponse
-	5, // 5: basic.v1.BasicService.Background:output_type -> basic.service.v1.BackgroundResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: basic.v1.BasicService.Hello:input_type -> basic.service.v1.HelloRequest
+	1,  // 1: basic.v1.BasicService.Talk:input_type -> basic.service.v1.TalkRequest
+	2,  // 2: basic.v1.BasicService.Background:input_type -> basic.service.v1.BackgroundRequest
+	3,  // 3: basic.v1.BasicService.ListBackgroundJobs:input_type -> basic.service.v1.ListBackgroundJobsRequest
+	4,  // 4: basic.v1.BasicService.GetBackgroundJob:input_type -> basic.service.v1.GetBackgroundJobRequest
+	5,  // 5: basic.v1.BasicService.CancelBackgroundJob:input_type -> basic.service.v1.CancelBackgroundJobRequest
+	6,  // 6: basic.v1.BasicService.Hello:output_type -> basic.service.v1.HelloResponse
+	7,  // 7: basic.v1.BasicService.Talk:output_type -> basic.service.v1.TalkResponse
+	8,  // 8: basic.v1.BasicService.Background:output_type -> basic.service.v1.BackgroundResponse
+	9,  // 9: basic.v1.BasicService.ListBackgroundJobs:output_type -> basic.service.v1.ListBackgroundJobsResponse
+	10, // 10: basic.v1.BasicService.GetBackgroundJob:output_type -> basic.service.v1.GetBackgroundJobResponse
+	11, // 11: basic.v1.BasicService.CancelBackgroundJob:output_type -> basic.service.v1.CancelBackgroundJobResponse
+	6,  // [6:12] is the sub-list for method output_type
+	0,  // [0:6] is the sub-list for method input_type
+	0,  // [0:0] is the sub-list for extension type_name
+	0,  // [0:0] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
 }
 
 func init() { file_basic_v1_basic_proto_init() }
@@ -1,3 +1,8 @@
+// BasicService gRPC API Definition
+//
+// This file defines the main gRPC service interface for the Basic Service,
+// providing simple greeting, conversational, and background processing capabilities.
+
 // Code generated by protoc-gen-connect-go. DO NOT EDIT.
 //
 // Source: basic/v1/basic.proto
@@ -40,13 +45,38 @@ const (
 	BasicServiceTalkProcedure = "/basic.v1.BasicService/Talk"
 	// BasicServiceBackgroundProcedure is the fully-qualified name of the BasicService's Background RPC.
 	BasicServiceBackgroundProcedure = "/basic.v1.BasicService/Background"
+	// BasicServiceListBackgroundJobsProcedure is the fully-qualified name of the BasicService's
+	// ListBackgroundJobs RPC.
+	BasicServiceListBackgroundJobsProcedure = "/basic.v1.BasicService/ListBackgroundJobs"
+	// BasicServiceGetBackgroundJobProcedure is the fully-qualified name of the BasicService's
+	// GetBackgroundJob RPC.
+	BasicServiceGetBackgroundJobProcedure = "/basic.v1.BasicService/GetBackgroundJob"
+	// BasicServiceCancelBackgroundJobProcedure is the fully-qualified name of the BasicService's
+	// CancelBackgroundJob RPC.
+	BasicServiceCancelBackgroundJobProcedure = "/basic.v1.BasicService/CancelBackgroundJob"
 )
 
 // BasicServiceClient is a client for the basic.v1.BasicService service.
 type BasicServiceClient interface {
+	// Hello returns a personalized greeting wrapped in a Cloud Event.
 	Hello(context.Context, *connect.Request[v1.HelloRequest]) (*connect.Response[v1.HelloResponse], error)
+	// Talk provides a bidirectional streaming chat interface using an ELIZA-like bot.
 	Talk(context.Context) *connect.BidiStreamForClient[v1.TalkRequest, v1.TalkResponse]
+	// Background starts a long-running operation and streams status updates as it progresses.
+	// Uses fan-out/fan-in pattern to call multiple external services concurrently.
+	// The first update is sent right away and carries the job ID, further updates follow on
+	// every downstream response and state change, and as heartbeat while nothing changes.
+	// Requests with a job ID reattach to that job, replaying its current state and following
+	// it until completion.
 	Background(context.Context, *connect.Request[v1.BackgroundRequest]) (*connect.ServerStreamForClient[v1.BackgroundResponse], error)
+	// ListBackgroundJobs returns a page of the background jobs started by the caller,
+	// optionally filtered by state.
+	ListBackgroundJobs(context.Context, *connect.Request[v1.ListBackgroundJobsRequest]) (*connect.Response[v1.ListBackgroundJobsResponse], error)
+	// GetBackgroundJob returns a background job started by the caller.
+	GetBackgroundJob(context.Context, *connect.Request[v1.GetBackgroundJobRequest]) (*connect.Response[v1.GetBackgroundJobResponse], error)
+	// CancelBackgroundJob stops a running background job started by the caller. Its
+	// in-flight downstream calls are cancelled and the job ends in STATE_CANCELLED.
+	CancelBackgroundJob(context.Context, *connect.Request[v1.CancelBackgroundJobRequest]) (*connect.Response[v1.CancelBackgroundJobResponse], error)
 }
 
 // NewBasicServiceClient constructs a client for the basic.v1.BasicService service. By default, it
@@ -78,14 +108,38 @@ func NewBasicServiceClient(httpClient connect.HTTPClient, baseURL string, opts .
 			connect.WithSchema(basicServiceMethods.ByName("Background")),
 			connect.WithClientOptions(opts...),
 		),
+		listBackgroundJobs: connect.NewClient[v1.ListBackgroundJobsRequest, v1.ListBackgroundJobsResponse](
+			httpClient,
+			baseURL+BasicServiceListBackgroundJobsProcedure,
+			connect.WithSchema(basicServiceMethods.ByName("ListBackgroundJobs")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		getBackgroundJob: connect.NewClient[v1.GetBackgroundJobRequest, v1.GetBackgroundJobResponse](
+			httpClient,
+			baseURL+BasicServiceGetBackgroundJobProcedure,
+			connect.WithSchema(basicServiceMethods.ByName("GetBackgroundJob")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		cancelBackgroundJob: connect.NewClient[v1.CancelBackgroundJobRequest, v1.CancelBackgroundJobResponse](
+			httpClient,
+			baseURL+BasicServiceCancelBackgroundJobProcedure,
+			connect.WithSchema(basicServiceMethods.ByName("CancelBackgroundJob")),
+			connect.WithIdempotency(connect.IdempotencyIdempotent),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // basicServiceClient implements BasicServiceClient.
 type basicServiceClient struct {
-	hello      *connect.Client[v1.HelloRequest, v1.HelloResponse]
-	talk       *connect.Client[v1.TalkRequest, v1.TalkResponse]
-	background *connect.Client[v1.BackgroundRequest, v1.BackgroundResponse]
+	hello               *connect.Client[v1.HelloRequest, v1.HelloResponse]
+	talk                *connect.Client[v1.TalkRequest, v1.TalkResponse]
+	background          *connect.Client[v1.BackgroundRequest, v1.BackgroundResponse]
+	listBackgroundJobs  *connect.Client[v1.ListBackgroundJobsRequest, v1.ListBackgroundJobsResponse]
+	getBackgroundJob    *connect.Client[v1.GetBackgroundJobRequest, v1.GetBackgroundJobResponse]
+	cancelBackgroundJob *connect.Client[v1.CancelBackgroundJobRequest, v1.CancelBackgroundJobResponse]
 }
 
 // Hello calls basic.v1.BasicService.Hello.
@@ -103,11 +157,42 @@ func (c *basicServiceClient) Background(ctx context.Context, req *connect.Reques
 	return c.background.CallServerStream(ctx, req)
 }
 
+// ListBackgroundJobs calls basic.v1.BasicService.ListBackgroundJobs.
+func (c *basicServiceClient) ListBackgroundJobs(ctx context.Context, req *connect.Request[v1.ListBackgroundJobsRequest]) (*connect.Response[v1.ListBackgroundJobsResponse], error) {
+	return c.listBackgroundJobs.CallUnary(ctx, req)
+}
+
+// GetBackgroundJob calls basic.v1.BasicService.GetBackgroundJob.
+func (c *basicServiceClient) GetBackgroundJob(ctx context.Context, req *connect.Request[v1.GetBackgroundJobRequest]) (*connect.Response[v1.GetBackgroundJobResponse], error) {
+	return c.getBackgroundJob.CallUnary(ctx, req)
+}
+
+// CancelBackgroundJob calls basic.v1.BasicService.CancelBackgroundJob.
+func (c *basicServiceClient) CancelBackgroundJob(ctx context.Context, req *connect.Request[v1.CancelBackgroundJobRequest]) (*connect.Response[v1.CancelBackgroundJobResponse], error) {
+	return c.cancelBackgroundJob.CallUnary(ctx, req)
+}
+
 // BasicServiceHandler is an implementation of the basic.v1.BasicService service.
 type BasicServiceHandler interface {
+	// Hello returns a personalized greeting wrapped in a Cloud Event.
 	Hello(context.Context, *connect.Request[v1.HelloRequest]) (*connect.Response[v1.HelloResponse], error)
+	// Talk provides a bidirectional streaming chat interface using an ELIZA-like bot.
 	Talk(context.Context, *connect.BidiStream[v1.TalkRequest, v1.TalkResponse]) error
+	// Background starts a long-running operation and streams status updates as it progresses.
+	// Uses fan-out/fan-in pattern to call multiple external services concurrently.
+	// The first update is sent right away and carries the job ID, further updates follow on
+	// every downstream response and state change, and as heartbeat while nothing changes.
+	// Requests with a job ID reattach to that job, replaying its current state and following
+	// it until completion.
 	Background(context.Context, *connect.Request[v1.BackgroundRequest], *connect.ServerStream[v1.BackgroundResponse]) error
+	// ListBackgroundJobs returns a page of the background jobs started by the caller,
+	// optionally filtered by state.
+	ListBackgroundJobs(context.Context, *connect.Request[v1.ListBackgroundJobsRequest]) (*connect.Response[v1.ListBackgroundJobsResponse], error)
+	// GetBackgroundJob returns a background job started by the caller.
+	GetBackgroundJob(context.Context, *connect.Request[v1.GetBackgroundJobRequest]) (*connect.Response[v1.GetBackgroundJobResponse], error)
+	// CancelBackgroundJob stops a running background job started by the caller. Its
+	// in-flight downstream calls are cancelled and the job ends in STATE_CANCELLED.
+	CancelBackgroundJob(context.Context, *connect.Request[v1.CancelBackgroundJobRequest]) (*connect.Response[v1.CancelBackgroundJobResponse], error)
 }
 
 // NewBasicServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -135,6 +220,27 @@ func NewBasicServiceHandler(svc BasicServiceHandler, opts ...connect.HandlerOpti
 		connect.WithSchema(basicServiceMethods.ByName("Background")),
 		connect.WithHandlerOptions(opts...),
 	)
+	basicServiceListBackgroundJobsHandler := connect.NewUnaryHandler(
+		BasicServiceListBackgroundJobsProcedure,
+		svc.ListBackgroundJobs,
+		connect.WithSchema(basicServiceMethods.ByName("ListBackgroundJobs")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	basicServiceGetBackgroundJobHandler := connect.NewUnaryHandler(
+		BasicServiceGetBackgroundJobProcedure,
+		svc.GetBackgroundJob,
+		connect.WithSchema(basicServiceMethods.ByName("GetBackgroundJob")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	basicServiceCancelBackgroundJobHandler := connect.NewUnaryHandler(
+		BasicServiceCancelBackgroundJobProcedure,
+		svc.CancelBackgroundJob,
+		connect.WithSchema(basicServiceMethods.ByName("CancelBackgroundJob")),
+		connect.WithIdempotency(connect.IdempotencyIdempotent),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/basic.v1.BasicService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case BasicServiceHelloProcedure:
@@ -143,6 +249,12 @@ func NewBasicServiceHandler(svc BasicServiceHandler, opts ...connect.HandlerOpti
 			basicServiceTalkHandler.ServeHTTP(w, r)
 		case BasicServiceBackgroundProcedure:
 			basicServiceBackgroundHandler.ServeHTTP(w, r)
+		case BasicServiceListBackgroundJobsProcedure:
+			basicServiceListBackgroundJobsHandler.ServeHTTP(w, r)
+		case BasicServiceGetBackgroundJobProcedure:
+			basicServiceGetBackgroundJobHandler.ServeHTTP(w, r)
+		case BasicServiceCancelBackgroundJobProcedure:
+			basicServiceCancelBackgroundJobHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -163,3 +275,15 @@ func (UnimplementedBasicServiceHandler) Talk(context.Context, *connect.BidiStrea
 func (UnimplementedBasicServiceHandler) Background(context.Context, *connect.Request[v1.BackgroundRequest], *connect.ServerStream[v1.BackgroundResponse]) error {
 	return connect.NewError(connect.CodeUnimplemented, errors.New("basic.v1.BasicService.Background is not implemented"))
 }
+
+func (UnimplementedBasicServiceHandler) ListBackgroundJobs(context.Context, *connect.Request[v1.ListBackgroundJobsRequest]) (*connect.Response[v1.ListBackgroundJobsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("basic.v1.BasicService.ListBackgroundJobs is not implemented"))
+}
+
+func (UnimplementedBasicServiceHandler) GetBackgroundJob(context.Context, *connect.Request[v1.GetBackgroundJobRequest]) (*connect.Response[v1.GetBackgroundJobResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("basic.v1.BasicService.GetBackgroundJob is not implemented"))
+}
+
+func (UnimplementedBasicServiceHandler) CancelBackgroundJob(context.Context, *connect.Request[v1.CancelBackgroundJobRequest]) (*connect.Response[v1.CancelBackgroundJobResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("basic.v1.BasicService.CancelBackgroundJob is not implemented"))
+}
@@ -0,0 +1,89 @@
+// Package basicerrors describes the errors returned by the BasicService. Every
+// error carries a google.rpc.ErrorInfo with a machine readable reason and a
+// google.rpc.RequestInfo with the request ID to quote in support requests.
+// Depending on the error it also carries a google.rpc.RetryInfo, a
+// google.rpc.BadRequest or, if the server enables it, a google.rpc.DebugInfo.
+//
+// Clients extract them with FromError:
+//
+//	resp, err := client.Hello(ctx, req)
+//	if details, ok := basicerrors.FromError(err); ok && details.RetryDelay > 0 {
+//		time.Sleep(details.RetryDelay)
+//	}
+package basicerrors
+
+import (
+	"errors"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Domain is the ErrorInfo domain of errors returned by the service.
+const Domain = "basic-grpc-service"
+
+// Reasons reported in ErrorInfo. Errors without a specific reason report the
+// name of their code in upper case, e.g. DEADLINE_EXCEEDED.
+const (
+	ReasonUnauthenticated     = "UNAUTHENTICATED"       // Credentials are missing or invalid
+	ReasonPermissionDenied    = "PERMISSION_DENIED"     // The authorization policy does not allow the call
+	ReasonRateLimited         = "RATE_LIMITED"          // Too many calls, retry after RetryDelay
+	ReasonStreamLimitExceeded = "STREAM_LIMIT_EXCEEDED" // Too many open streams, retry after RetryDelay
+	ReasonInvalidRequest      = "INVALID_REQUEST"       // The request violates constraints, see FieldViolations
+	ReasonValidationFailed    = "VALIDATION_FAILED"     // The constraints of the request could not be evaluated
+	ReasonStreamClosed        = "STREAM_CLOSED"         // Sending or receiving a stream message failed
+	ReasonCanceled            = "CANCELED"              // The call was canceled while being handled
+	ReasonJobNotFound         = "JOB_NOT_FOUND"         // No Background job with the requested ID, see the job_id metadata
+	ReasonJobFinished         = "JOB_FINISHED"          // The Background job already finished and cannot be cancelled
+	ReasonJobLimitExceeded    = "JOB_LIMIT_EXCEEDED"    // Too many running Background jobs, see the limit metadata
+	ReasonEncodingFailed      = "ENCODING_FAILED"       // The response event could not be encoded
+	ReasonPanic               = "PANIC"                 // The handler panicked, see the correlation ID
+)
+
+// Details are the error details attached to an error by the service.
+type Details struct {
+	Code      connect.Code
+	Message   string
+	Reason    string            // ErrorInfo reason, one of the Reason constants
+	Domain    string            // ErrorInfo domain, Domain for errors of the service
+	Metadata  map[string]string // ErrorInfo metadata, e.g. the limit that rejected the call
+	RequestID string            // RequestInfo request ID, the X-Request-Id of the call
+
+	RetryDelay      time.Duration                           // RetryInfo delay, 0 if the call should not be retried as is
+	FieldViolations []*errdetails.BadRequest_FieldViolation // BadRequest violations of invalid requests
+	Debug           *errdetails.DebugInfo                   // DebugInfo, nil unless enabled on the server
+}
+
+// FromError returns the details of err, an error returned by a Connect client
+// of the service. It returns false if err is not a Connect error. Details the
+// error does not carry are left empty.
+func FromError(err error) (*Details, bool) {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return nil, false
+	}
+
+	details := &Details{Code: connectErr.Code(), Message: connectErr.Message()}
+	for _, detail := range connectErr.Details() {
+		value, err := detail.Value()
+		if err != nil {
+			continue // Detail of a type not linked into the client
+		}
+		switch value := value.(type) {
+		case *errdetails.ErrorInfo:
+			details.Reason = value.GetReason()
+			details.Domain = value.GetDomain()
+			details.Metadata = value.GetMetadata()
+		case *errdetails.RequestInfo:
+			details.RequestID = value.GetRequestId()
+		case *errdetails.RetryInfo:
+			details.RetryDelay = value.GetRetryDelay().AsDuration()
+		case *errdetails.BadRequest:
+			details.FieldViolations = value.GetFieldViolations()
+		case *errdetails.DebugInfo:
+			details.Debug = value
+		}
+	}
+	return details, true
+}